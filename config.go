@@ -1,18 +1,106 @@
 package blog
 
-import "github.com/nicolasbonnici/gorest/database"
+import (
+	"time"
+
+	"github.com/nicolasbonnici/gorest-blog/importer"
+	"github.com/nicolasbonnici/gorest-blog/jobs"
+	"github.com/nicolasbonnici/gorest-blog/likes"
+	"github.com/nicolasbonnici/gorest-blog/media"
+	"github.com/nicolasbonnici/gorest-blog/permalink"
+	"github.com/nicolasbonnici/gorest-blog/policy"
+	"github.com/nicolasbonnici/gorest-blog/render"
+	"github.com/nicolasbonnici/gorest-blog/search"
+	"github.com/nicolasbonnici/gorest-blog/slug"
+	"github.com/nicolasbonnici/gorest-blog/trash"
+	"github.com/nicolasbonnici/gorest/database"
+)
 
 type Config struct {
-	Database         database.Database
-	PaginationLimit  int
+	Database           database.Database
+	PaginationLimit    int
 	MaxPaginationLimit int
-	EnableImporter   bool
+	EnableImporter     bool
+	// ImportWorkers is the number of import jobs run concurrently by this instance.
+	ImportWorkers int
+	// ImportDownloadMedia lets import requests ask for download_media, which makes the
+	// server fetch the images linked by the imported content. Only public addresses are
+	// fetched; it is off by default.
+	ImportDownloadMedia bool
+
+	// AdminRole is the role allowed to update or delete content owned by other users.
+	AdminRole string
+	// EditorRole is the role allowed to read every post, including other authors' drafts.
+	// Admins may too.
+	EditorRole string
+	// RoleResolver extracts the caller's role from the request. Defaults to policy.LocalsRoleResolver.
+	RoleResolver policy.RoleResolver
+
+	// SearchLanguage is the PostgreSQL text search configuration expected by /search (e.g. "english",
+	// "french"). The index is built by the reindex command (search/cli); a mismatch is logged at startup.
+	SearchLanguage string
+
+	// SiteURL is the public base URL of the blog, used to build absolute links in feeds,
+	// metadata and the sitemap.
+	SiteURL         string
+	SiteTitle       string
+	SiteDescription string
+	// FeedItemCount is the number of posts listed in RSS, Atom and JSON feeds.
+	FeedItemCount int
+	// PostPathTemplate is the path of a post page under SiteURL, e.g. "/posts/{slug}" or
+	// "/{year}/{month}/{slug}". See permalink.Validate for the accepted placeholders.
+	PostPathTemplate string
+
+	// EnablePublisher starts the background job publishing scheduled posts when they are due.
+	EnablePublisher bool
+	PublishInterval time.Duration
+
+	// TrashRetention is how long deleted posts and comments stay in the trash before being
+	// purged for good. Zero keeps them until they are purged by hand.
+	TrashRetention time.Duration
+
+	// SlugScope is either slug.ScopeGlobal (slugs unique across the blog) or slug.ScopeAuthor (unique per author).
+	SlugScope slug.Scope
+
+	// RequireCommentApproval holds new comments as pending until a moderator approves them.
+	// Posts may override it with their requireCommentApproval field.
+	RequireCommentApproval bool
+
+	// ReactionKinds lists the reactions users may leave on posts and comments (e.g. "like",
+	// "unicorn", "bookmark"). The first one is recorded by plain likes.
+	ReactionKinds []string
+
+	// RenderCacheSize is the number of posts whose rendered HTML is kept in memory (0 disables the cache).
+	RenderCacheSize int
+
+	// MediaStorage keeps uploaded files. Defaults to a media.LocalStorage rooted at MediaDir.
+	MediaStorage media.Storage
+	MediaDir     string
+	// MediaMaxSize is the size limit of an uploaded file, in bytes.
+	MediaMaxSize int64
 }
 
 func DefaultConfig() Config {
 	return Config{
-		PaginationLimit:    10,
-		MaxPaginationLimit: 1000,
-		EnableImporter:     false,
+		PaginationLimit:        10,
+		MaxPaginationLimit:     1000,
+		EnableImporter:         false,
+		ImportWorkers:          importer.DefaultWorkers,
+		AdminRole:              policy.DefaultAdminRole,
+		EditorRole:             policy.DefaultEditorRole,
+		SearchLanguage:         search.DefaultLanguage,
+		SiteURL:                "http://localhost:8000",
+		SiteTitle:              "Blog",
+		FeedItemCount:          20,
+		PostPathTemplate:       permalink.DefaultTemplate,
+		EnablePublisher:        true,
+		PublishInterval:        jobs.DefaultPublishInterval,
+		TrashRetention:         trash.DefaultRetention,
+		SlugScope:              slug.ScopeGlobal,
+		RequireCommentApproval: false,
+		ReactionKinds:          []string{likes.DefaultKind},
+		RenderCacheSize:        render.DefaultCacheSize,
+		MediaDir:               "uploads",
+		MediaMaxSize:           media.DefaultMaxSize,
 	}
 }
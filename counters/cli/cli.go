@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/nicolasbonnici/gorest-blog/counters"
+	"github.com/nicolasbonnici/gorest/database"
+	_ "github.com/nicolasbonnici/gorest/database/postgres"
+)
+
+// Run recomputes the like and comment counters and returns an exit code
+// This is the main entry point for the counters repair CLI
+func Run(args []string) int {
+	fs := flag.NewFlagSet("repair-counters", flag.ExitOnError)
+	timeout := fs.Duration("timeout", 10*time.Minute, "Maximum duration of the repair")
+
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing flags: %v\n", err)
+		return 1
+	}
+
+	// Get database URL from environment
+	databaseURL := os.Getenv("DATABASE_URL")
+	if databaseURL == "" {
+		fmt.Fprintln(os.Stderr, "Error: DATABASE_URL environment variable is required")
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	db, err := database.Open("postgres", databaseURL)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to connect to database: %v\n", err)
+		return 1
+	}
+	defer func() { _ = db.Close() }()
+
+	fixed, err := counters.Repair(ctx, db)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Repair failed: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Counters repaired: %d posts and comments corrected\n", fixed)
+	return 0
+}
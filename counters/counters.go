@@ -0,0 +1,21 @@
+package counters
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nicolasbonnici/gorest/database"
+)
+
+// Repair recomputes the like and comment counters of every post and comment from the likes
+// and comment tables, and returns the number of rows whose counters were wrong.
+//
+// Counters are kept up to date by database triggers; repairing is only needed after rows were
+// changed with the triggers disabled (restores, bulk loads, ...).
+func Repair(ctx context.Context, db database.Database) (int, error) {
+	var fixed int
+	if err := db.QueryRow(ctx, "SELECT blog_repair_counters()").Scan(&fixed); err != nil {
+		return 0, fmt.Errorf("failed to repair counters: %w", err)
+	}
+	return fixed, nil
+}
@@ -0,0 +1,223 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// DefaultContext is the number of unchanged lines shown around each change.
+	DefaultContext = 3
+	// MaxEditDistance bounds the search for a shortest edit script, whose cost grows with
+	// the number D of changed lines: O(D²) memory and O((N+M)·D) time. Revisions differing
+	// by more lines are diffed as a whole replacement of the changed region.
+	MaxEditDistance = 1000
+)
+
+type opKind byte
+
+const (
+	opEqual  opKind = ' '
+	opDelete opKind = '-'
+	opInsert opKind = '+'
+)
+
+type op struct {
+	kind opKind
+	text string
+}
+
+// Unified returns the unified diff turning a into b, or an empty string when
+// they are identical. fromName and toName label the two sides in the header.
+func Unified(fromName, toName, a, b string, context int) string {
+	if a == b {
+		return ""
+	}
+	if context < 0 {
+		context = DefaultContext
+	}
+
+	ops := lineOps(splitLines(a), splitLines(b))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+
+	// aLine and bLine count the lines of each side consumed before ops[i].
+	aLines := make([]int, len(ops)+1)
+	bLines := make([]int, len(ops)+1)
+	for i, o := range ops {
+		aLines[i+1], bLines[i+1] = aLines[i], bLines[i]
+		if o.kind != opInsert {
+			aLines[i+1]++
+		}
+		if o.kind != opDelete {
+			bLines[i+1]++
+		}
+	}
+
+	for i := 0; i < len(ops); {
+		if ops[i].kind == opEqual {
+			i++
+			continue
+		}
+
+		// Extend the hunk while the next change is close enough to share context.
+		last := i
+		for j := i + 1; j < len(ops); j++ {
+			if ops[j].kind == opEqual {
+				continue
+			}
+			if j-last > 2*context {
+				break
+			}
+			last = j
+		}
+
+		start := max(0, i-context)
+		end := min(len(ops), last+context+1)
+		writeHunk(&sb, ops[start:end], aLines[start], bLines[start], aLines[end]-aLines[start], bLines[end]-bLines[start])
+		i = end
+	}
+
+	return sb.String()
+}
+
+func writeHunk(sb *strings.Builder, ops []op, aStart, bStart, aLen, bLen int) {
+	// Line numbers are 1-based, except for empty ranges which point at the preceding line.
+	if aLen > 0 {
+		aStart++
+	}
+	if bLen > 0 {
+		bStart++
+	}
+
+	fmt.Fprintf(sb, "@@ -%d,%d +%d,%d @@\n", aStart, aLen, bStart, bLen)
+	for _, o := range ops {
+		sb.WriteByte(byte(o.kind))
+		sb.WriteString(o.text)
+		sb.WriteByte('\n')
+	}
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// lineOps computes an edit script between a and b. Their common leading and trailing
+// lines are kept as is, and the lines in between are compared with Myers' algorithm.
+func lineOps(a, b []string) []op {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]op, 0, len(a)+len(b)-prefix-suffix)
+	for _, line := range a[:prefix] {
+		ops = append(ops, op{kind: opEqual, text: line})
+	}
+	ops = append(ops, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, op{kind: opEqual, text: line})
+	}
+
+	return ops
+}
+
+// myers computes a shortest edit script between a and b. When it would take more than
+// MaxEditDistance inserted and deleted lines, a is replaced by b as a whole instead.
+func myers(a, b []string) []op {
+	n, m := len(a), len(b)
+	limit := min(n+m, MaxEditDistance)
+	offset := limit + 1
+	v := make([]int, 2*limit+3)
+
+	// trace[d] holds the diagonals -d-1..d+1 of v before step d, the only ones step d
+	// reads, so that the trace grows with D² instead of D·(N+M).
+	trace := make([][]int, 0)
+	found := false
+
+search:
+	for d := 0; d <= limit; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break search
+			}
+		}
+	}
+
+	if !found {
+		return replaceOps(a, b)
+	}
+
+	ops := make([]op, 0, n+m)
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		// diagonal k of v before step d is trace[d][k+d+1].
+		v := trace[d]
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && v[k-1+d+1] < v[k+1+d+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[prevK+d+1]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			ops = append(ops, op{kind: opEqual, text: a[x-1]})
+			x--
+			y--
+		}
+
+		if d > 0 {
+			if x == prevX {
+				ops = append(ops, op{kind: opInsert, text: b[y-1]})
+			} else {
+				ops = append(ops, op{kind: opDelete, text: a[x-1]})
+			}
+		}
+
+		x, y = prevX, prevY
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+
+	return ops
+}
+
+// replaceOps deletes every line of a, then inserts every line of b.
+func replaceOps(a, b []string) []op {
+	ops := make([]op, 0, len(a)+len(b))
+	for _, line := range a {
+		ops = append(ops, op{kind: opDelete, text: line})
+	}
+	for _, line := range b {
+		ops = append(ops, op{kind: opInsert, text: line})
+	}
+	return ops
+}
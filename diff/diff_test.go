@@ -0,0 +1,142 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{
+			name: "identical",
+			a:    "one\ntwo\n",
+			b:    "one\ntwo\n",
+			want: "",
+		},
+		{
+			name: "both empty",
+			want: "",
+		},
+		{
+			name: "from empty",
+			b:    "one\ntwo\n",
+			want: "--- a\n+++ b\n@@ -0,0 +1,2 @@\n+one\n+two\n",
+		},
+		{
+			name: "to empty",
+			a:    "one\ntwo\n",
+			want: "--- a\n+++ b\n@@ -1,2 +0,0 @@\n-one\n-two\n",
+		},
+		{
+			name: "full replace",
+			a:    "one\ntwo\n",
+			b:    "three\nfour\n",
+			want: "--- a\n+++ b\n@@ -1,2 +1,2 @@\n-one\n-two\n+three\n+four\n",
+		},
+		{
+			name: "insert at start",
+			a:    "two\nthree\n",
+			b:    "one\ntwo\nthree\n",
+			want: "--- a\n+++ b\n@@ -1,2 +1,3 @@\n+one\n two\n three\n",
+		},
+		{
+			name: "insert at end",
+			a:    "one\ntwo\n",
+			b:    "one\ntwo\nthree\n",
+			want: "--- a\n+++ b\n@@ -1,2 +1,3 @@\n one\n two\n+three\n",
+		},
+		{
+			name: "delete at start",
+			a:    "one\ntwo\nthree\n",
+			b:    "two\nthree\n",
+			want: "--- a\n+++ b\n@@ -1,3 +1,2 @@\n-one\n two\n three\n",
+		},
+		{
+			name: "delete at end",
+			a:    "one\ntwo\nthree\n",
+			b:    "one\ntwo\n",
+			want: "--- a\n+++ b\n@@ -1,3 +1,2 @@\n one\n two\n-three\n",
+		},
+		{
+			name: "change in the middle",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			b:    "1\n2\n3\n4\nfive\n6\n7\n8\n9\n",
+			want: "--- a\n+++ b\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			name: "distant changes make separate hunks",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			b:    "one\n2\n3\n4\n5\n6\n7\n8\n9\nten\n",
+			want: "--- a\n+++ b\n@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n@@ -7,4 +7,4 @@\n 7\n 8\n 9\n-10\n+ten\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Unified("a", "b", tt.a, tt.b, DefaultContext); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLineOpsAppliesToB(t *testing.T) {
+	tests := []struct{ a, b string }{
+		{"a\nb\nc\na\nb\nb\na", "c\nb\na\nb\na\nc"},
+		{"x\ny\nz", "z\ny\nx"},
+		{"same\nsame\nsame", "same"},
+	}
+
+	for _, tt := range tests {
+		a, b := splitLines(tt.a), splitLines(tt.b)
+		ops := lineOps(a, b)
+
+		var gotA, gotB []string
+		edits := 0
+		for _, o := range ops {
+			if o.kind != opInsert {
+				gotA = append(gotA, o.text)
+			}
+			if o.kind != opDelete {
+				gotB = append(gotB, o.text)
+			}
+			if o.kind != opEqual {
+				edits++
+			}
+		}
+		if strings.Join(gotA, "\n") != strings.Join(a, "\n") || strings.Join(gotB, "\n") != strings.Join(b, "\n") {
+			t.Errorf("%q -> %q: ops do not rebuild both sides: %v", tt.a, tt.b, ops)
+		}
+		if tt.a == "a\nb\nc\na\nb\nb\na" && edits != 5 {
+			t.Errorf("got %d edits, want the shortest edit script (5)", edits)
+		}
+	}
+}
+
+func TestLineOpsBoundsEditDistance(t *testing.T) {
+	// Two unrelated revisions: the shortest edit script is never searched for.
+	a := make([]string, 0, 2*MaxEditDistance)
+	b := make([]string, 0, 2*MaxEditDistance)
+	for i := 0; i < 2*MaxEditDistance; i++ {
+		a = append(a, fmt.Sprintf("a%d", i))
+		b = append(b, fmt.Sprintf("b%d", i))
+	}
+	a = append([]string{"header"}, append(a, "footer")...)
+	b = append([]string{"header"}, append(b, "footer")...)
+
+	ops := lineOps(a, b)
+	if len(ops) != 2+4*MaxEditDistance {
+		t.Fatalf("got %d ops, want %d", len(ops), 2+4*MaxEditDistance)
+	}
+	if ops[0] != (op{kind: opEqual, text: "header"}) || ops[len(ops)-1] != (op{kind: opEqual, text: "footer"}) {
+		t.Errorf("common lines are not kept: first %v, last %v", ops[0], ops[len(ops)-1])
+	}
+	if ops[1].kind != opDelete || ops[len(ops)-2].kind != opInsert {
+		t.Errorf("changed region is not replaced as a whole: %v ... %v", ops[1], ops[len(ops)-2])
+	}
+}
@@ -0,0 +1,42 @@
+package feed
+
+import "time"
+
+// Feed is the format-agnostic description of a syndication feed.
+type Feed struct {
+	Title       string
+	Description string
+	// Link is the URL of the HTML page the feed describes.
+	Link string
+	// FeedURL is the canonical URL of the feed document itself.
+	FeedURL string
+	Updated time.Time
+	Items   []Item
+}
+
+type Item struct {
+	ID    string
+	Title string
+	Link  string
+	// Content is the plain-text body of the item.
+	Content string
+	// ContentHTML is the sanitized HTML body of the item. Formats prefer it over Content
+	// when it is set.
+	ContentHTML string
+	Author      string
+	Tags        []string
+	Published   time.Time
+	Updated     time.Time
+}
+
+// LastModified returns the most recent update time among the feed items,
+// or the feed's own Updated time when it has no items.
+func (f Feed) LastModified() time.Time {
+	latest := f.Updated
+	for _, item := range f.Items {
+		if item.Updated.After(latest) {
+			latest = item.Updated
+		}
+	}
+	return latest
+}
@@ -0,0 +1,211 @@
+package feed
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	published = time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	updated   = time.Date(2024, 1, 16, 9, 30, 0, 0, time.FixedZone("CET", 3600))
+)
+
+func testFeed() Feed {
+	return Feed{
+		Title:       "My Blog",
+		Description: "Notes about Go",
+		Link:        "https://blog.example.com",
+		FeedURL:     "https://blog.example.com/feed.xml",
+		Updated:     published,
+		Items: []Item{
+			{
+				ID:          "0b6a5f4e-0000-4000-8000-000000000001",
+				Title:       "Tom & Jerry <3",
+				Link:        "https://blog.example.com/posts/tom-and-jerry",
+				Content:     "Plain text",
+				ContentHTML: "<p>Some <strong>HTML</strong></p>",
+				Author:      "Jane Doe",
+				Tags:        []string{"go", "web"},
+				Published:   published,
+				Updated:     updated,
+			},
+			{
+				ID:        "0b6a5f4e-0000-4000-8000-000000000002",
+				Title:     "Text only",
+				Link:      "https://blog.example.com/posts/text-only",
+				Content:   "Only text",
+				Published: published,
+			},
+		},
+	}
+}
+
+func TestLastModified(t *testing.T) {
+	if got := testFeed().LastModified(); !got.Equal(updated) {
+		t.Errorf("got %s, want the latest item update %s", got, updated)
+	}
+	if got := (Feed{Updated: published}).LastModified(); !got.Equal(published) {
+		t.Errorf("got %s, want the feed update %s without items", got, published)
+	}
+}
+
+func TestRSS(t *testing.T) {
+	body, err := RSS(testFeed())
+	if err != nil {
+		t.Fatalf("RSS: %v", err)
+	}
+	if !strings.HasPrefix(string(body), xml.Header) {
+		t.Error("missing XML header")
+	}
+
+	var doc struct {
+		Version string `xml:"version,attr"`
+		Channel struct {
+			Title         string `xml:"title"`
+			LastBuildDate string `xml:"lastBuildDate"`
+			Items         []struct {
+				Title       string   `xml:"title"`
+				GUID        string   `xml:"guid"`
+				PubDate     string   `xml:"pubDate"`
+				Creator     string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
+				Categories  []string `xml:"category"`
+				Description string   `xml:"description"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		t.Fatalf("invalid RSS document: %v\n%s", err, body)
+	}
+
+	if doc.Version != "2.0" || doc.Channel.Title != "My Blog" {
+		t.Errorf("got version %q, title %q", doc.Version, doc.Channel.Title)
+	}
+	if doc.Channel.LastBuildDate != "Tue, 16 Jan 2024 08:30:00 +0000" {
+		t.Errorf("got last build date %q", doc.Channel.LastBuildDate)
+	}
+	if len(doc.Channel.Items) != 2 {
+		t.Fatalf("got %d items, want 2", len(doc.Channel.Items))
+	}
+
+	item := doc.Channel.Items[0]
+	if item.Title != "Tom & Jerry <3" || item.GUID != "0b6a5f4e-0000-4000-8000-000000000001" {
+		t.Errorf("got title %q, guid %q", item.Title, item.GUID)
+	}
+	if item.PubDate != "Mon, 15 Jan 2024 10:00:00 +0000" || item.Creator != "Jane Doe" {
+		t.Errorf("got pubDate %q, creator %q", item.PubDate, item.Creator)
+	}
+	if strings.Join(item.Categories, ",") != "go,web" {
+		t.Errorf("got categories %v", item.Categories)
+	}
+	if item.Description != "<p>Some <strong>HTML</strong></p>" {
+		t.Errorf("got description %q, want the HTML content", item.Description)
+	}
+	if !strings.Contains(string(body), "&lt;strong&gt;") {
+		t.Error("HTML description is not entity-encoded")
+	}
+	if doc.Channel.Items[1].Description != "Only text" {
+		t.Errorf("got description %q, want the text content", doc.Channel.Items[1].Description)
+	}
+}
+
+func TestAtom(t *testing.T) {
+	body, err := Atom(testFeed())
+	if err != nil {
+		t.Fatalf("Atom: %v", err)
+	}
+
+	var doc struct {
+		XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+		ID      string   `xml:"id"`
+		Updated string   `xml:"updated"`
+		Links   []struct {
+			Href string `xml:"href,attr"`
+			Rel  string `xml:"rel,attr"`
+		} `xml:"link"`
+		Entries []struct {
+			ID      string `xml:"id"`
+			Updated string `xml:"updated"`
+			Author  *struct {
+				Name string `xml:"name"`
+			} `xml:"author"`
+			Categories []struct {
+				Term string `xml:"term,attr"`
+			} `xml:"category"`
+			Content struct {
+				Type  string `xml:"type,attr"`
+				Value string `xml:",chardata"`
+			} `xml:"content"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		t.Fatalf("invalid Atom document: %v\n%s", err, body)
+	}
+
+	if doc.ID != "https://blog.example.com/feed.xml" || doc.Updated != "2024-01-16T08:30:00Z" {
+		t.Errorf("got id %q, updated %q", doc.ID, doc.Updated)
+	}
+	if len(doc.Links) != 2 || doc.Links[0].Rel != "self" || doc.Links[1].Href != "https://blog.example.com" {
+		t.Errorf("got links %+v", doc.Links)
+	}
+	if len(doc.Entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(doc.Entries))
+	}
+
+	entry := doc.Entries[0]
+	if entry.ID != "urn:uuid:0b6a5f4e-0000-4000-8000-000000000001" || entry.Author == nil || entry.Author.Name != "Jane Doe" {
+		t.Errorf("got id %q, author %+v", entry.ID, entry.Author)
+	}
+	if len(entry.Categories) != 2 || entry.Categories[1].Term != "web" {
+		t.Errorf("got categories %+v", entry.Categories)
+	}
+	if entry.Content.Type != "html" || entry.Content.Value != "<p>Some <strong>HTML</strong></p>" {
+		t.Errorf("got content %+v", entry.Content)
+	}
+
+	text := doc.Entries[1]
+	if text.Author != nil || text.Content.Type != "text" || text.Content.Value != "Only text" {
+		t.Errorf("got author %+v, content %+v", text.Author, text.Content)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	body, err := JSON(testFeed())
+	if err != nil {
+		t.Fatalf("JSON: %v", err)
+	}
+
+	var doc map[string]any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		t.Fatalf("invalid JSON Feed: %v", err)
+	}
+	if doc["version"] != "https://jsonfeed.org/version/1.1" || doc["home_page_url"] != "https://blog.example.com" {
+		t.Errorf("got version %v, home page %v", doc["version"], doc["home_page_url"])
+	}
+
+	items, _ := doc["items"].([]any)
+	if len(items) != 2 {
+		t.Fatalf("got %d items, want 2", len(items))
+	}
+
+	item := items[0].(map[string]any)
+	if item["content_html"] != "<p>Some <strong>HTML</strong></p>" || item["content_text"] != "Plain text" {
+		t.Errorf("got content %v / %v", item["content_html"], item["content_text"])
+	}
+	if item["date_published"] != "2024-01-15T10:00:00Z" || item["date_modified"] != "2024-01-16T08:30:00Z" {
+		t.Errorf("got dates %v / %v", item["date_published"], item["date_modified"])
+	}
+	authors, _ := item["authors"].([]any)
+	if len(authors) != 1 || authors[0].(map[string]any)["name"] != "Jane Doe" {
+		t.Errorf("got authors %v", item["authors"])
+	}
+
+	text := items[1].(map[string]any)
+	for _, key := range []string{"content_html", "date_modified", "authors", "tags"} {
+		if _, ok := text[key]; ok {
+			t.Errorf("item without %s has the key", key)
+		}
+	}
+}
@@ -4,20 +4,25 @@ go 1.25.1
 
 require (
 	github.com/gofiber/fiber/v2 v2.52.10
+	github.com/microcosm-cc/bluemonday v1.0.27
 	github.com/nicolasbonnici/gorest v0.4.1
 	github.com/nicolasbonnici/gorest-auth v0.1.4
 	github.com/schollz/progressbar/v3 v3.14.1
+	github.com/yuin/goldmark v1.7.8
 	golang.org/x/crypto v0.46.0
+	golang.org/x/net v0.26.0
 )
 
 require (
 	github.com/andybalholm/brotli v1.1.0 // indirect
+	github.com/aymerick/douceur v0.2.0 // indirect
 	github.com/gabriel-vasile/mimetype v1.4.12 // indirect
 	github.com/go-playground/locales v0.14.1 // indirect
 	github.com/go-playground/universal-translator v0.18.1 // indirect
 	github.com/go-playground/validator/v10 v10.30.1 // indirect
 	github.com/golang-jwt/jwt/v5 v5.3.0 // indirect
 	github.com/google/uuid v1.6.0 // indirect
+	github.com/gorilla/css v1.0.1 // indirect
 	github.com/jackc/pgpassfile v1.0.0 // indirect
 	github.com/jackc/pgservicefile v0.0.0-20240606120523-5a60cdf6a761 // indirect
 	github.com/jackc/pgx/v5 v5.7.6 // indirect
@@ -0,0 +1,88 @@
+package hooks
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/nicolasbonnici/gorest-blog/models"
+	"github.com/nicolasbonnici/gorest-blog/types"
+	"github.com/nicolasbonnici/gorest/database"
+	"github.com/nicolasbonnici/gorest/hooks"
+)
+
+// CommentHooks assigns the author and the moderation status of new comments.
+type CommentHooks struct {
+	DB database.Database
+	// RequireApproval holds new comments as pending, unless their post overrides it.
+	RequireApproval bool
+}
+
+func (h *CommentHooks) StateProcessor(ctx context.Context, operation hooks.Operation, id any, comment *models.Comment) error {
+	if operation == hooks.OperationCreate {
+		if userID := ctx.Value("user_id"); userID != nil {
+			if uid, ok := userID.(string); ok {
+				comment.UserId = &uid
+			}
+		}
+
+		required, err := h.requiresApproval(ctx, comment.PostId)
+		if err != nil {
+			return err
+		}
+
+		// Commenters never choose the status of their own comment.
+		comment.Status = string(types.CommentStatusApproved)
+		if required {
+			comment.Status = string(types.CommentStatusPending)
+			log.Printf("StateProcessor: Comment held for moderation")
+		}
+	}
+
+	if operation == hooks.OperationCreate || operation == hooks.OperationUpdate {
+		// Comments are only moved to the trash by Delete, and restored from it.
+		comment.DeletedAt = nil
+	}
+
+	return nil
+}
+
+func (h *CommentHooks) BeforeQuery(ctx context.Context, operation hooks.Operation, query string, args []any) (string, []any, error) {
+	// Trashed comments are never read by id; listings exclude them in their where clause.
+	if operation == hooks.OperationGetByID {
+		query, args = scoped(query, args, scopeAlias+".deleted_at IS NULL")
+	}
+	return query, args, nil
+}
+
+func (h *CommentHooks) AfterQuery(ctx context.Context, operation hooks.Operation, query string, args []any, result any, err error) error {
+	return nil
+}
+
+func (h *CommentHooks) OverrideQuery(ctx context.Context, operation hooks.Operation, id any, model *models.Comment) (query string, args []any, skip bool) {
+	return "", nil, false
+}
+
+func (h *CommentHooks) SerializeOne(ctx context.Context, operation hooks.Operation, comment *models.Comment) error {
+	return nil
+}
+
+func (h *CommentHooks) SerializeMany(ctx context.Context, operation hooks.Operation, comments *[]models.Comment) error {
+	return nil
+}
+
+// requiresApproval applies the post's require_comment_approval override, falling back to
+// the global setting.
+func (h *CommentHooks) requiresApproval(ctx context.Context, postID *string) (bool, error) {
+	if postID == nil || h.DB == nil {
+		return h.RequireApproval, nil
+	}
+
+	var required bool
+	query := "SELECT COALESCE((SELECT require_comment_approval FROM post WHERE id::text = $1 AND deleted_at IS NULL), $2)"
+	if err := h.DB.QueryRow(ctx, query, *postID, h.RequireApproval).Scan(&required); err != nil {
+		return false, fmt.Errorf("failed to read comment approval setting: %w", err)
+	}
+
+	return required, nil
+}
@@ -2,16 +2,21 @@ package hooks
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"log"
 	"strings"
 	"time"
 
 	"github.com/nicolasbonnici/gorest-blog/models"
+	"github.com/nicolasbonnici/gorest-blog/render"
 	"github.com/nicolasbonnici/gorest-blog/types"
+	"github.com/nicolasbonnici/gorest-blog/visibility"
 	"github.com/nicolasbonnici/gorest/hooks"
 )
 
+// ErrPublishedAtRequired is returned when a post is scheduled without a publication date.
+var ErrPublishedAtRequired = errors.New("publishedAt is required for a scheduled post")
+
 type PostHooks struct{}
 
 func (h *PostHooks) StateProcessor(ctx context.Context, operation hooks.Operation, id any, post *models.Post) error {
@@ -30,29 +35,22 @@ func (h *PostHooks) StateProcessor(ctx context.Context, operation hooks.Operatio
 	}
 
 	if operation == hooks.OperationCreate || operation == hooks.OperationUpdate {
-		if post.Status == string(types.PostStatusPublished) && post.PublishedAt == nil {
-			now := time.Now()
-			post.PublishedAt = &now
-			log.Printf("StateProcessor: Set publishedAt to %s for post being published", now.Format(time.RFC3339))
-		}
+		return PrepareSave(post)
 	}
 
 	return nil
 }
 
+// BeforeQuery restricts reads by id to the posts visible to the user of ctx that are not
+// in the trash. Listings apply the same visibility.Scope in their where clause.
 func (h *PostHooks) BeforeQuery(ctx context.Context, operation hooks.Operation, query string, args []any) (string, []any, error) {
-	log.Printf("[PostHooks] BeforeQuery called - operation: %s, query: %s", operation, query)
-
-	if operation == hooks.OperationGetAll || operation == hooks.OperationGetByID {
-		if !isAuthenticated(ctx) {
-			modifiedQuery, modifiedArgs := addStatusFilter(query, args)
-			log.Printf("BeforeQuery: Added status filter for unauthenticated user")
-			log.Printf("Original query: %s", query)
-			log.Printf("Modified query: %s", modifiedQuery)
-			return modifiedQuery, modifiedArgs, nil
-		} else {
-			log.Printf("BeforeQuery: Skipping status filter for authenticated user")
+	if operation == hooks.OperationGetByID {
+		cond := scopeAlias + ".deleted_at IS NULL"
+		visible, visibleArgs := visibility.ForContext(ctx).Condition(scopeAlias)
+		if visible != "" {
+			cond += " AND " + visible
 		}
+		query, args = scoped(query, args, cond, visibleArgs...)
 	}
 	return query, args, nil
 }
@@ -66,11 +64,6 @@ func (h *PostHooks) OverrideQuery(ctx context.Context, operation hooks.Operation
 }
 
 func (h *PostHooks) SerializeOne(ctx context.Context, operation hooks.Operation, post *models.Post) error {
-	if post.UserId != nil {
-		log.Printf("SerializeOne: Post %s has userId: %s", post.Id, *post.UserId)
-	} else {
-		log.Printf("SerializeOne: Post %s has NIL userId", post.Id)
-	}
 	return nil
 }
 
@@ -81,58 +74,54 @@ func (h *PostHooks) SerializeMany(ctx context.Context, operation hooks.Operation
 	return nil
 }
 
-func isAuthenticated(ctx context.Context) bool {
-	if userID := ctx.Value("user_id"); userID != nil {
-		if userIDStr, ok := userID.(string); ok && userIDStr != "" {
-			log.Printf("[Auth] User authenticated: %s", userIDStr)
-			return true
-		}
-	}
-	log.Printf("[Auth] User not authenticated")
-	return false
-}
-
-func addStatusFilter(query string, args []any) (string, []any) {
-	queryLower := strings.ToLower(query)
-
-	newArgs := make([]any, len(args))
-	copy(newArgs, args)
-	newArgs = append(newArgs, types.PostStatusPublished)
+// PrepareSave sets the fields derived on every save of post: its content stats, and its
+// status and publication date, which follow each other. ErrPublishedAtRequired is returned
+// for a scheduled post without a publication date.
+func PrepareSave(post *models.Post) error {
+	// Posts are only moved to the trash by Delete, and restored from it.
+	post.DeletedAt = nil
+	SetContentStats(post)
 
-	placeholder := fmt.Sprintf("$%d", len(newArgs))
+	now := time.Now()
 
-	if strings.Contains(queryLower, " where ") {
-		parts := strings.SplitN(query, " WHERE ", 2)
-		if len(parts) == 2 {
-			return parts[0] + " WHERE " + parts[1] + fmt.Sprintf(" AND status = %s", placeholder), newArgs
+	switch types.PostStatus(post.Status) {
+	case types.PostStatusPublished:
+		if post.PublishedAt == nil {
+			post.PublishedAt = &now
+			log.Printf("PrepareSave: Set publishedAt to %s for post being published", now.Format(time.RFC3339))
+		} else if post.PublishedAt.After(now) {
+			// A future publication date is honored: the publisher job will publish it when due.
+			post.Status = string(types.PostStatusScheduled)
+			log.Printf("PrepareSave: Scheduled post for %s", post.PublishedAt.Format(time.RFC3339))
 		}
-		parts = strings.SplitN(query, " where ", 2)
-		if len(parts) == 2 {
-			return parts[0] + " where " + parts[1] + fmt.Sprintf(" AND status = %s", placeholder), newArgs
+	case types.PostStatusScheduled:
+		if post.PublishedAt == nil {
+			return ErrPublishedAtRequired
 		}
-	}
-
-	if strings.Contains(queryLower, " order by ") {
-		parts := strings.SplitN(query, " ORDER BY ", 2)
-		if len(parts) == 2 {
-			return parts[0] + fmt.Sprintf(" WHERE status = %s ORDER BY ", placeholder) + parts[1], newArgs
-		}
-		parts = strings.SplitN(query, " order by ", 2)
-		if len(parts) == 2 {
-			return parts[0] + fmt.Sprintf(" WHERE status = %s order by ", placeholder) + parts[1], newArgs
+		if !post.PublishedAt.After(now) {
+			post.Status = string(types.PostStatusPublished)
+			log.Printf("PrepareSave: Published scheduled post whose publishedAt is already past")
 		}
 	}
 
-	if strings.Contains(queryLower, " limit ") {
-		parts := strings.SplitN(query, " LIMIT ", 2)
-		if len(parts) == 2 {
-			return parts[0] + fmt.Sprintf(" WHERE status = %s LIMIT ", placeholder) + parts[1], newArgs
-		}
-		parts = strings.SplitN(query, " limit ", 2)
-		if len(parts) == 2 {
-			return parts[0] + fmt.Sprintf(" WHERE status = %s limit ", placeholder) + parts[1], newArgs
+	return nil
+}
+
+// SetContentStats computes the excerpt, word count and reading time of post from its
+// content. A non-empty ExcerptOverride is used as the excerpt instead of the computed one.
+func SetContentStats(post *models.Post) {
+	stats := render.Analyze(post.Content)
+	post.WordCount = stats.WordCount
+	post.ReadingTimeMinutes = stats.ReadingTimeMinutes
+	post.Excerpt = stats.Excerpt
+
+	if post.ExcerptOverride != nil {
+		override := strings.TrimSpace(*post.ExcerptOverride)
+		if override == "" {
+			post.ExcerptOverride = nil
+		} else {
+			post.ExcerptOverride = &override
+			post.Excerpt = override
 		}
 	}
-
-	return query + fmt.Sprintf(" WHERE status = %s", placeholder), newArgs
 }
@@ -0,0 +1,26 @@
+package hooks
+
+import (
+	"fmt"
+	"strings"
+)
+
+// scopeAlias names the generated query wrapped by scoped.
+const scopeAlias = "scoped"
+
+// scoped restricts the rows returned by query to those matching cond. query is selected
+// from as a subquery rather than edited, so neither its case, its subqueries nor the text
+// of its arguments can defeat the restriction. cond refers to the columns through
+// scopeAlias and to condArgs through "?" markers.
+//
+// It is only meant for reads by id: a LIMIT or OFFSET in query would apply before cond.
+func scoped(query string, args []any, cond string, condArgs ...any) (string, []any) {
+	newArgs := make([]any, len(args), len(args)+len(condArgs))
+	copy(newArgs, args)
+	for _, arg := range condArgs {
+		newArgs = append(newArgs, arg)
+		cond = strings.Replace(cond, "?", fmt.Sprintf("$%d", len(newArgs)), 1)
+	}
+
+	return fmt.Sprintf("SELECT * FROM (%s) AS %s WHERE %s", query, scopeAlias, cond), newArgs
+}
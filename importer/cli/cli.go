@@ -10,6 +10,9 @@ import (
 	"github.com/nicolasbonnici/gorest-blog/importer"
 	"github.com/nicolasbonnici/gorest-blog/importer/engines"
 	_ "github.com/nicolasbonnici/gorest-blog/importer/engines/devto"
+	_ "github.com/nicolasbonnici/gorest-blog/importer/engines/hashnode"
+	_ "github.com/nicolasbonnici/gorest-blog/importer/engines/medium"
+	"github.com/nicolasbonnici/gorest-blog/media"
 	"github.com/nicolasbonnici/gorest/database"
 	_ "github.com/nicolasbonnici/gorest/database/postgres"
 	"github.com/schollz/progressbar/v3"
@@ -71,9 +74,13 @@ func Run(args []string) int {
 	username := fs.String("username", "", "Username to import articles from")
 	articleURL := fs.String("url", "", "Specific article URL to import")
 	articleID := fs.String("id", "", "Specific article ID to import")
+	archive := fs.String("archive", "", "Account export archive to import (e.g. Medium's export ZIP)")
 	userID := fs.String("user-id", "", "User ID to assign imported posts to (required)")
-	update := fs.Bool("update", false, "Update existing posts with matching titles")
+	update := fs.Bool("update", false, "Update posts previously imported from the same articles")
 	dryRun := fs.Bool("dry-run", false, "Preview import without saving")
+	downloadMedia := fs.Bool("download-media", false, "Copy cover and inline images into local media storage")
+	mediaDir := fs.String("media-dir", "uploads", "Directory where downloaded media are stored")
+	siteURL := fs.String("site-url", "http://localhost:8000", "Public base URL of the blog, used in rewritten image links")
 	listEngines := fs.Bool("list-engines", false, "List available engines")
 
 	if err := fs.Parse(args); err != nil {
@@ -97,8 +104,8 @@ func Run(args []string) int {
 		return 1
 	}
 
-	if *username == "" && *articleURL == "" && *articleID == "" {
-		fmt.Fprintln(os.Stderr, "Error: one of --username, --url, or --id must be provided")
+	if *username == "" && *articleURL == "" && *articleID == "" && *archive == "" {
+		fmt.Fprintln(os.Stderr, "Error: one of --username, --url, --id, or --archive must be provided")
 		fs.Usage()
 		return 1
 	}
@@ -126,6 +133,9 @@ func Run(args []string) int {
 	repo := importer.NewRepository(db)
 	reporter := &CLIProgressReporter{}
 	service := importer.NewService(repo, reporter)
+	if *downloadMedia {
+		service.WithMedia(media.NewStore(db, media.NewLocalStorage(*mediaDir), media.DefaultMaxSize, *siteURL))
+	}
 
 	// Build import options
 	opts := importer.ImportOptions{
@@ -134,8 +144,10 @@ func Run(args []string) int {
 		Username:       *username,
 		ArticleURL:     *articleURL,
 		ArticleID:      *articleID,
+		ArchivePath:    *archive,
 		UpdateExisting: *update,
 		DryRun:         *dryRun,
+		DownloadMedia:  *downloadMedia,
 	}
 
 	// Show dry-run notice
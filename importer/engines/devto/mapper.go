@@ -18,6 +18,12 @@ func MapPost(devtoArticle DevToArticle) engines.Post {
 		updatedAt = devtoArticle.EditedAt.Format("2006-01-02T15:04:05Z07:00")
 	}
 
+	// dev.to sets canonical_url to the article itself unless the author points it elsewhere.
+	canonicalURL := devtoArticle.CanonicalURL
+	if canonicalURL == "" {
+		canonicalURL = devtoArticle.URL
+	}
+
 	return engines.Post{
 		ID:          fmt.Sprintf("%d", devtoArticle.ID),
 		Title:       devtoArticle.Title,
@@ -27,6 +33,12 @@ func MapPost(devtoArticle DevToArticle) engines.Post {
 		UpdatedAt:   updatedAt,
 		URL:         devtoArticle.URL,
 		SourceID:    fmt.Sprintf("devto-%d", devtoArticle.ID),
+		Tags:        []string(devtoArticle.TagList),
+
+		Description:        devtoArticle.Description,
+		ReadingTimeMinutes: devtoArticle.ReadingTimeMin,
+		CoverImage:         devtoArticle.CoverImage,
+		CanonicalURL:       canonicalURL,
 	}
 }
 
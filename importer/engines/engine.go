@@ -2,6 +2,7 @@ package engines
 
 import (
 	"context"
+	"io"
 )
 
 // Engine defines the interface that all import engines must implement.
@@ -20,3 +21,12 @@ type Engine interface {
 	// FetchByURL fetches a single post from its URL on the source platform
 	FetchByURL(ctx context.Context, url string) (*Post, error)
 }
+
+// ArchiveEngine is implemented by engines that can also read the posts of an account
+// export archive downloaded from the source platform (e.g. Medium's export ZIP).
+type ArchiveEngine interface {
+	Engine
+
+	// FetchArchive reads the posts of the archive r, of size bytes
+	FetchArchive(ctx context.Context, r io.ReaderAt, size int64) ([]Post, error)
+}
@@ -0,0 +1,322 @@
+package hashnode
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultEndpoint = "https://gql.hashnode.com"
+	DefaultTimeout  = 30 * time.Second
+	// EndpointEnv overrides the GraphQL endpoint of the registered engine, e.g. to point it
+	// at a stand-in server.
+	EndpointEnv = "HASHNODE_ENDPOINT"
+	// pageSize is the number of items requested per page of a connection.
+	pageSize = 20
+)
+
+type Client struct {
+	endpoint   string
+	httpClient *http.Client
+}
+
+type Post struct {
+	ID                string     `json:"id"`
+	Slug              string     `json:"slug"`
+	Title             string     `json:"title"`
+	Subtitle          string     `json:"subtitle"`
+	URL               string     `json:"url"`
+	CanonicalURL      string     `json:"canonicalUrl"`
+	PublishedAt       string     `json:"publishedAt"`
+	UpdatedAt         string     `json:"updatedAt"`
+	ReadTimeInMinutes int        `json:"readTimeInMinutes"`
+	Content           Content    `json:"content"`
+	Tags              []Tag      `json:"tags"`
+	CoverImage        *Image     `json:"coverImage"`
+	Author            PostAuthor `json:"author"`
+}
+
+type Content struct {
+	Markdown string `json:"markdown"`
+}
+
+type Tag struct {
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+type Image struct {
+	URL string `json:"url"`
+}
+
+type PostAuthor struct {
+	Username string `json:"username"`
+}
+
+type pageInfo struct {
+	HasNextPage bool   `json:"hasNextPage"`
+	EndCursor   string `json:"endCursor"`
+}
+
+const postFields = `
+fragment PostFields on Post {
+  id
+  slug
+  title
+  subtitle
+  url
+  canonicalUrl
+  publishedAt
+  updatedAt
+  readTimeInMinutes
+  content { markdown }
+  tags { name slug }
+  coverImage { url }
+  author { username }
+}`
+
+const userPublicationsQuery = `
+query UserPublications($username: String!, $first: Int!, $after: String) {
+  user(username: $username) {
+    publications(first: $first, after: $after) {
+      edges { node { host } }
+      pageInfo { hasNextPage endCursor }
+    }
+  }
+}`
+
+const publicationPostsQuery = `
+query PublicationPosts($host: String!, $first: Int!, $after: String) {
+  publication(host: $host) {
+    posts(first: $first, after: $after) {
+      edges { node { ...PostFields } }
+      pageInfo { hasNextPage endCursor }
+    }
+  }
+}` + postFields
+
+const postQuery = `
+query Post($id: ID!) {
+  post(id: $id) { ...PostFields }
+}` + postFields
+
+const publicationPostQuery = `
+query PublicationPost($host: String!, $slug: String!) {
+  publication(host: $host) {
+    post(slug: $slug) { ...PostFields }
+  }
+}` + postFields
+
+// NewClient returns a client of the endpoint set in HASHNODE_ENDPOINT, or of the public
+// Hashnode API.
+func NewClient() *Client {
+	endpoint := strings.TrimSpace(os.Getenv(EndpointEnv))
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+	return NewClientWithEndpoint(endpoint)
+}
+
+// NewClientWithEndpoint returns a client of a Hashnode-compatible GraphQL endpoint.
+func NewClientWithEndpoint(endpoint string) *Client {
+	return &Client{
+		endpoint: endpoint,
+		httpClient: &http.Client{
+			Timeout: DefaultTimeout,
+		},
+	}
+}
+
+// GetUserPublicationHosts returns the hosts of the publications of username.
+func (c *Client) GetUserPublicationHosts(ctx context.Context, username string) ([]string, error) {
+	if username == "" {
+		return nil, fmt.Errorf("username cannot be empty")
+	}
+
+	hosts := make([]string, 0)
+	after := ""
+	for {
+		var data struct {
+			User *struct {
+				Publications struct {
+					Edges []struct {
+						Node struct {
+							Host string `json:"host"`
+						} `json:"node"`
+					} `json:"edges"`
+					PageInfo pageInfo `json:"pageInfo"`
+				} `json:"publications"`
+			} `json:"user"`
+		}
+
+		variables := map[string]any{"username": username, "first": pageSize, "after": cursor(after)}
+		if err := c.doQuery(ctx, userPublicationsQuery, variables, &data); err != nil {
+			return nil, fmt.Errorf("failed to fetch publications of user %s: %w", username, err)
+		}
+		if data.User == nil {
+			return nil, fmt.Errorf("user %s not found", username)
+		}
+
+		for _, edge := range data.User.Publications.Edges {
+			hosts = append(hosts, edge.Node.Host)
+		}
+
+		page := data.User.Publications.PageInfo
+		if !page.HasNextPage || page.EndCursor == "" {
+			return hosts, nil
+		}
+		after = page.EndCursor
+	}
+}
+
+// GetPublicationPosts returns every post of the publication at host, following the
+// cursors of the posts connection.
+func (c *Client) GetPublicationPosts(ctx context.Context, host string) ([]Post, error) {
+	if host == "" {
+		return nil, fmt.Errorf("publication host cannot be empty")
+	}
+
+	posts := make([]Post, 0)
+	after := ""
+	for {
+		var data struct {
+			Publication *struct {
+				Posts struct {
+					Edges []struct {
+						Node Post `json:"node"`
+					} `json:"edges"`
+					PageInfo pageInfo `json:"pageInfo"`
+				} `json:"posts"`
+			} `json:"publication"`
+		}
+
+		variables := map[string]any{"host": host, "first": pageSize, "after": cursor(after)}
+		if err := c.doQuery(ctx, publicationPostsQuery, variables, &data); err != nil {
+			return nil, fmt.Errorf("failed to fetch posts of publication %s: %w", host, err)
+		}
+		if data.Publication == nil {
+			return nil, fmt.Errorf("publication %s not found", host)
+		}
+
+		for _, edge := range data.Publication.Posts.Edges {
+			posts = append(posts, edge.Node)
+		}
+
+		page := data.Publication.Posts.PageInfo
+		if !page.HasNextPage || page.EndCursor == "" {
+			return posts, nil
+		}
+		after = page.EndCursor
+	}
+}
+
+func (c *Client) GetPostByID(ctx context.Context, id string) (*Post, error) {
+	if id == "" {
+		return nil, fmt.Errorf("post ID cannot be empty")
+	}
+
+	var data struct {
+		Post *Post `json:"post"`
+	}
+	if err := c.doQuery(ctx, postQuery, map[string]any{"id": id}, &data); err != nil {
+		return nil, fmt.Errorf("failed to fetch post %s: %w", id, err)
+	}
+	if data.Post == nil {
+		return nil, fmt.Errorf("post %s not found", id)
+	}
+
+	return data.Post, nil
+}
+
+func (c *Client) GetPostBySlug(ctx context.Context, host, slug string) (*Post, error) {
+	var data struct {
+		Publication *struct {
+			Post *Post `json:"post"`
+		} `json:"publication"`
+	}
+	variables := map[string]any{"host": host, "slug": slug}
+	if err := c.doQuery(ctx, publicationPostQuery, variables, &data); err != nil {
+		return nil, fmt.Errorf("failed to fetch post %s of %s: %w", slug, host, err)
+	}
+	if data.Publication == nil {
+		return nil, fmt.Errorf("publication %s not found", host)
+	}
+	if data.Publication.Post == nil {
+		return nil, fmt.Errorf("post %s not found in publication %s", slug, host)
+	}
+
+	return data.Publication.Post, nil
+}
+
+// cursor returns the after variable of a connection: null for its first page.
+func cursor(after string) any {
+	if after == "" {
+		return nil
+	}
+	return after
+}
+
+func (c *Client) doQuery(ctx context.Context, query string, variables map[string]any, result any) error {
+	payload, err := json.Marshal(map[string]any{"query": query, "variables": variables})
+	if err != nil {
+		return fmt.Errorf("failed to encode query: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "GoREST-Blog-Importer/1.0")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var response struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if len(response.Errors) > 0 {
+		messages := make([]string, 0, len(response.Errors))
+		for _, e := range response.Errors {
+			messages = append(messages, e.Message)
+		}
+		return fmt.Errorf("API returned errors: %s", strings.Join(messages, "; "))
+	}
+
+	if len(response.Data) == 0 || string(response.Data) == "null" {
+		return fmt.Errorf("API returned no data")
+	}
+	if err := json.Unmarshal(response.Data, result); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
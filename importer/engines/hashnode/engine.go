@@ -0,0 +1,110 @@
+package hashnode
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/nicolasbonnici/gorest-blog/importer/engines"
+)
+
+type Engine struct {
+	client *Client
+}
+
+func NewEngine() *Engine {
+	return NewEngineWithClient(NewClient())
+}
+
+func NewEngineWithClient(client *Client) *Engine {
+	return &Engine{
+		client: client,
+	}
+}
+
+func (e *Engine) Name() string {
+	return "hashnode"
+}
+
+// FetchByUsername fetches the posts written by username in their publications. A
+// publication host (e.g. "jane.hashnode.dev" or "blog.example.com") fetches every post of
+// that publication instead.
+func (e *Engine) FetchByUsername(ctx context.Context, username string) ([]engines.Post, error) {
+	username = strings.TrimPrefix(strings.TrimSpace(username), "@")
+
+	if strings.Contains(username, ".") {
+		posts, err := e.client.GetPublicationPosts(ctx, username)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch posts from Hashnode: %w", err)
+		}
+		return MapPosts(posts), nil
+	}
+
+	hosts, err := e.client.GetUserPublicationHosts(ctx, username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch posts from Hashnode: %w", err)
+	}
+
+	// Team publications also hold posts of other authors.
+	posts := make([]Post, 0)
+	seen := make(map[string]bool)
+	for _, host := range hosts {
+		publicationPosts, err := e.client.GetPublicationPosts(ctx, host)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch posts from Hashnode: %w", err)
+		}
+		for _, post := range publicationPosts {
+			if strings.EqualFold(post.Author.Username, username) && !seen[post.ID] {
+				seen[post.ID] = true
+				posts = append(posts, post)
+			}
+		}
+	}
+
+	return MapPosts(posts), nil
+}
+
+func (e *Engine) FetchByID(ctx context.Context, id string) (*engines.Post, error) {
+	hashnodePost, err := e.client.GetPostByID(ctx, strings.TrimSpace(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch post from Hashnode: %w", err)
+	}
+
+	post := MapPost(*hashnodePost)
+	return &post, nil
+}
+
+// FetchByURL fetches the post at postURL, https://<publication host>/<slug>.
+func (e *Engine) FetchByURL(ctx context.Context, postURL string) (*engines.Post, error) {
+	host, slug, err := parsePostURL(postURL)
+	if err != nil {
+		return nil, err
+	}
+
+	hashnodePost, err := e.client.GetPostBySlug(ctx, host, slug)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch post from Hashnode: %w", err)
+	}
+
+	post := MapPost(*hashnodePost)
+	return &post, nil
+}
+
+func parsePostURL(postURL string) (string, string, error) {
+	parsedURL, err := url.Parse(strings.TrimSpace(postURL))
+	if err != nil {
+		return "", "", fmt.Errorf("invalid URL: %w", err)
+	}
+
+	parts := strings.Split(strings.Trim(parsedURL.Path, "/"), "/")
+	if parsedURL.Host == "" || parts[len(parts)-1] == "" {
+		return "", "", fmt.Errorf("invalid Hashnode post URL format: %s", postURL)
+	}
+
+	return parsedURL.Host, parts[len(parts)-1], nil
+}
+
+func init() {
+	engines.Register(NewEngine())
+}
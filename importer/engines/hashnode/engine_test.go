@@ -0,0 +1,197 @@
+package hashnode
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/nicolasbonnici/gorest-blog/importer/engines"
+)
+
+type graphQLRequest struct {
+	Query     string         `json:"query"`
+	Variables map[string]any `json:"variables"`
+}
+
+// standIn is a local stand-in of the Hashnode GraphQL API answering with the recorded
+// responses of testdata.
+type standIn struct {
+	t *testing.T
+
+	mu       sync.Mutex
+	requests []graphQLRequest
+}
+
+func newTestEngine(t *testing.T) (*Engine, *standIn) {
+	t.Helper()
+
+	api := &standIn{t: t}
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+
+	return NewEngineWithClient(NewClientWithEndpoint(server.URL)), api
+}
+
+func (s *standIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req graphQLRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	s.mu.Unlock()
+
+	fixture := "not_found.json"
+	switch {
+	case strings.Contains(req.Query, "query UserPublications") && req.Variables["username"] == "jane":
+		fixture = "user_publications.json"
+	case strings.Contains(req.Query, "query PublicationPosts") && req.Variables["host"] == "jane.hashnode.dev":
+		fixture = "publication_posts_page1.json"
+		if req.Variables["after"] == "NjVhNGYxYzJlOGI5ZDEwMDEyYWIzNGNl" {
+			fixture = "publication_posts_page2.json"
+		}
+	case strings.Contains(req.Query, "query Post(") && req.Variables["id"] == "65a4f1c2e8b9d10012ab34cf":
+		fixture = "post.json"
+	case strings.Contains(req.Query, "query PublicationPost(") && req.Variables["host"] == "jane.hashnode.dev" && req.Variables["slug"] == "hello-hashnode":
+		fixture = "publication_post.json"
+	}
+
+	body, err := os.ReadFile(filepath.Join("testdata", fixture))
+	if err != nil {
+		s.t.Errorf("stand-in: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(body)
+}
+
+func (s *standIn) afterCursors() []any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cursors := make([]any, 0)
+	for _, req := range s.requests {
+		if strings.Contains(req.Query, "query PublicationPosts") {
+			cursors = append(cursors, req.Variables["after"])
+		}
+	}
+	return cursors
+}
+
+var helloHashnode = engines.Post{
+	ID:                 "65a4f1c2e8b9d10012ab34cd",
+	Title:              "Hello Hashnode",
+	Content:            "# Hello\n\nFirst post on **Hashnode**.\n",
+	Slug:               "hello-hashnode",
+	PublishedAt:        "2024-01-15T10:00:00Z",
+	UpdatedAt:          "2024-01-16T09:00:00Z",
+	URL:                "https://jane.hashnode.dev/hello-hashnode",
+	SourceID:           "hashnode-65a4f1c2e8b9d10012ab34cd",
+	Tags:               []string{"Go", "Blogging"},
+	Description:        "Moving my blog over",
+	ReadingTimeMinutes: 3,
+	CoverImage:         "https://cdn.hashnode.com/res/hashnode/image/upload/v1705312800/cover.png",
+	CanonicalURL:       "https://jane.hashnode.dev/hello-hashnode",
+}
+
+func TestFetchByUsername(t *testing.T) {
+	engine, api := newTestEngine(t)
+
+	posts, err := engine.FetchByUsername(context.Background(), "jane")
+	if err != nil {
+		t.Fatalf("FetchByUsername: %v", err)
+	}
+
+	// The guest post of another author is left out.
+	if len(posts) != 2 {
+		t.Fatalf("got %d posts, want 2", len(posts))
+	}
+	if !reflect.DeepEqual(posts[0], helloHashnode) {
+		t.Errorf("first post:\n got %+v\nwant %+v", posts[0], helloHashnode)
+	}
+
+	second := posts[1]
+	if second.SourceID != "hashnode-65a4f1c2e8b9d10012ab34cf" || second.CanonicalURL != "https://jane.example.com/cursor-pagination" {
+		t.Errorf("second post: got source id %q, canonical URL %q", second.SourceID, second.CanonicalURL)
+	}
+	if second.UpdatedAt != "" || second.CoverImage != "" {
+		t.Errorf("second post: got updated at %q, cover %q, want none", second.UpdatedAt, second.CoverImage)
+	}
+
+	want := []any{nil, "NjVhNGYxYzJlOGI5ZDEwMDEyYWIzNGNl"}
+	if got := api.afterCursors(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got after cursors %v, want %v", got, want)
+	}
+}
+
+func TestFetchByUsernamePublicationHost(t *testing.T) {
+	engine, _ := newTestEngine(t)
+
+	posts, err := engine.FetchByUsername(context.Background(), "jane.hashnode.dev")
+	if err != nil {
+		t.Fatalf("FetchByUsername: %v", err)
+	}
+	if len(posts) != 3 {
+		t.Errorf("got %d posts, want every post of the publication (3)", len(posts))
+	}
+}
+
+func TestFetchByUsernameUnknownUser(t *testing.T) {
+	engine, _ := newTestEngine(t)
+
+	_, err := engine.FetchByUsername(context.Background(), "nobody")
+	if err == nil || !strings.Contains(err.Error(), "User not found") {
+		t.Fatalf("FetchByUsername: got error %v, want the GraphQL error", err)
+	}
+}
+
+func TestFetchByID(t *testing.T) {
+	engine, _ := newTestEngine(t)
+
+	post, err := engine.FetchByID(context.Background(), "65a4f1c2e8b9d10012ab34cf")
+	if err != nil {
+		t.Fatalf("FetchByID: %v", err)
+	}
+	if post.Title != "Cursor pagination" || !reflect.DeepEqual(post.Tags, []string{"GraphQL"}) {
+		t.Errorf("got post %q with tags %v", post.Title, post.Tags)
+	}
+}
+
+func TestFetchByURL(t *testing.T) {
+	engine, _ := newTestEngine(t)
+
+	post, err := engine.FetchByURL(context.Background(), "https://jane.hashnode.dev/hello-hashnode")
+	if err != nil {
+		t.Fatalf("FetchByURL: %v", err)
+	}
+	if !reflect.DeepEqual(*post, helloHashnode) {
+		t.Errorf("got %+v\nwant %+v", *post, helloHashnode)
+	}
+
+	if _, err := engine.FetchByURL(context.Background(), "https://jane.hashnode.dev/"); err == nil {
+		t.Error("FetchByURL: expected an error for a URL without slug")
+	}
+}
+
+func TestNewClientEndpointFromEnvironment(t *testing.T) {
+	t.Setenv(EndpointEnv, "http://localhost:4000/graphql")
+
+	if got := NewClient().endpoint; got != "http://localhost:4000/graphql" {
+		t.Errorf("got endpoint %q, want the one of %s", got, EndpointEnv)
+	}
+}
@@ -0,0 +1,63 @@
+package hashnode
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nicolasbonnici/gorest-blog/importer/engines"
+)
+
+func MapPost(post Post) engines.Post {
+	tags := make([]string, 0, len(post.Tags))
+	for _, tag := range post.Tags {
+		if name := strings.TrimSpace(tag.Name); name != "" {
+			tags = append(tags, name)
+		}
+	}
+
+	coverImage := ""
+	if post.CoverImage != nil {
+		coverImage = post.CoverImage.URL
+	}
+
+	// canonicalUrl is only set when the post was first published elsewhere.
+	canonicalURL := post.CanonicalURL
+	if canonicalURL == "" {
+		canonicalURL = post.URL
+	}
+
+	return engines.Post{
+		ID:          post.ID,
+		Title:       post.Title,
+		Content:     post.Content.Markdown,
+		Slug:        post.Slug,
+		PublishedAt: formatTime(post.PublishedAt),
+		UpdatedAt:   formatTime(post.UpdatedAt),
+		URL:         post.URL,
+		SourceID:    fmt.Sprintf("hashnode-%s", post.ID),
+		Tags:        tags,
+
+		Description:        post.Subtitle,
+		ReadingTimeMinutes: post.ReadTimeInMinutes,
+		CoverImage:         coverImage,
+		CanonicalURL:       canonicalURL,
+	}
+}
+
+func MapPosts(posts []Post) []engines.Post {
+	mapped := make([]engines.Post, 0, len(posts))
+	for _, post := range posts {
+		mapped = append(mapped, MapPost(post))
+	}
+	return mapped
+}
+
+// formatTime converts an ISO 8601 date of the API to RFC3339, "" when it is empty or invalid.
+func formatTime(value string) string {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,184 @@
+package medium
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/nicolasbonnici/gorest-blog/importer/engines"
+	"golang.org/x/net/html"
+)
+
+// draftPrefix starts the file names of unpublished posts in the account export.
+const draftPrefix = "draft_"
+
+// ReadArchive returns the posts of a Medium account export: the HTML files of its posts/
+// directory. Drafts have no publish date nor URL, and are imported as drafts. The export
+// does not list the tags of posts.
+func ReadArchive(r io.ReaderAt, size int64) ([]engines.Post, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Medium export: %w", err)
+	}
+
+	files := make([]*zip.File, 0)
+	for _, file := range archive.File {
+		dir, name := path.Split(file.Name)
+		if path.Base(dir) == "posts" && strings.HasSuffix(name, ".html") {
+			files = append(files, file)
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("not a Medium export: no posts/*.html file found")
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+
+	posts := make([]engines.Post, 0, len(files))
+	for _, file := range files {
+		post, err := readArchivedPost(file)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, *post)
+	}
+
+	return posts, nil
+}
+
+func readArchivedPost(file *zip.File) (*engines.Post, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", file.Name, err)
+	}
+	defer func() { _ = rc.Close() }()
+
+	post, err := MapArchivedPost(path.Base(file.Name), rc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file.Name, err)
+	}
+	return post, nil
+}
+
+// MapArchivedPost maps the HTML file name of a post of the account export, e.g.
+// "2024-01-15_Hello-Go-abc123def456.html" or "draft_Notes-0123456789ab.html".
+func MapArchivedPost(name string, r io.Reader) (*engines.Post, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid HTML: %w", err)
+	}
+
+	id := postID(strings.TrimSuffix(name, ".html"))
+	title := text(find(doc, hasClass("p-name")))
+	if title == "" {
+		title = text(find(doc, isElement("title")))
+	}
+
+	body := find(doc, hasAttr("data-field", "body"))
+	if body == nil {
+		return nil, fmt.Errorf("the post has no body")
+	}
+	content := cleanContent(innerHTML(body), title)
+
+	post := &engines.Post{
+		ID:          id,
+		Title:       title,
+		Content:     content,
+		SourceID:    sourceID(id),
+		Description: text(find(doc, hasAttr("data-field", "subtitle"))),
+		CoverImage:  firstImage(content),
+	}
+
+	if !strings.HasPrefix(name, draftPrefix) {
+		if published := find(doc, hasClass("dt-published")); published != nil {
+			post.PublishedAt = formatTime(attr(published, "datetime"), "2006-01-02T15:04:05.000Z07:00", "2006-01-02T15:04:05Z07:00")
+		}
+	}
+
+	if canonical := find(doc, hasClass("p-canonical")); canonical != nil {
+		post.URL = stripQuery(attr(canonical, "href"))
+		post.CanonicalURL = post.URL
+		post.Slug = slugOf(post.URL, id)
+	}
+
+	return post, nil
+}
+
+// find returns the first node of the tree rooted at n matching match, depth first.
+func find(n *html.Node, match func(*html.Node) bool) *html.Node {
+	if match(n) {
+		return n
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if found := find(child, match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func isElement(name string) func(*html.Node) bool {
+	return func(n *html.Node) bool {
+		return n.Type == html.ElementNode && n.Data == name
+	}
+}
+
+func hasAttr(key, value string) func(*html.Node) bool {
+	return func(n *html.Node) bool {
+		return n.Type == html.ElementNode && attr(n, key) == value
+	}
+}
+
+func hasClass(class string) func(*html.Node) bool {
+	return func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return false
+		}
+		for _, c := range strings.Fields(attr(n, "class")) {
+			if c == class {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// text returns the text of n with its whitespace collapsed, "" when n is nil.
+func text(n *html.Node) string {
+	if n == nil {
+		return ""
+	}
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+func innerHTML(n *html.Node) string {
+	var buf bytes.Buffer
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		// Rendering only fails on writer errors, which a bytes.Buffer never returns.
+		_ = html.Render(&buf, child)
+	}
+	return buf.String()
+}
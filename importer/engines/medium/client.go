@@ -0,0 +1,162 @@
+package medium
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultBaseURL = "https://medium.com"
+	DefaultTimeout = 30 * time.Second
+)
+
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// Feed is the RSS 2.0 feed of a Medium user or publication. Medium only lists the latest
+// posts in it.
+type Feed struct {
+	Items []FeedItem `xml:"channel>item"`
+}
+
+type FeedItem struct {
+	Title      string   `xml:"title"`
+	Link       string   `xml:"link"`
+	GUID       string   `xml:"guid"`
+	Categories []string `xml:"category"`
+	Creator    string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	PubDate    string   `xml:"pubDate"`
+	Updated    string   `xml:"http://www.w3.org/2005/Atom updated"`
+	Content    string   `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+}
+
+func NewClient() *Client {
+	return NewClientWithBaseURL(DefaultBaseURL)
+}
+
+// NewClientWithBaseURL returns a client for a Medium-compatible server, e.g. a stand-in
+// server in tests.
+func NewClientWithBaseURL(baseURL string) *Client {
+	return &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{
+			Timeout: DefaultTimeout,
+		},
+	}
+}
+
+// GetFeed fetches the feed of a user ("@name" or "name") or of a publication ("pub:name").
+func (c *Client) GetFeed(ctx context.Context, name string) (*Feed, error) {
+	path, err := feedPath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.get(ctx, c.baseURL+path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch feed of %s: %w", name, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var feed Feed
+	if err := xml.NewDecoder(resp.Body).Decode(&feed); err != nil {
+		return nil, fmt.Errorf("failed to decode feed of %s: %w", name, err)
+	}
+
+	return &feed, nil
+}
+
+// ResolvePostURL returns the URL of post id, which Medium redirects to from /p/<id>.
+func (c *Client) ResolvePostURL(ctx context.Context, id string) (string, error) {
+	if !isPostID(id) {
+		return "", fmt.Errorf("invalid Medium post ID: %s", id)
+	}
+
+	resp, err := c.get(ctx, fmt.Sprintf("%s/p/%s", c.baseURL, id))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve post %s: %w", id, err)
+	}
+	_ = resp.Body.Close()
+
+	return resp.Request.URL.String(), nil
+}
+
+// feedSource returns the feed listing the post at postURL: the feed of its author or of
+// its publication.
+func (c *Client) feedSource(postURL string) (string, error) {
+	parsedURL, err := url.Parse(postURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %w", err)
+	}
+
+	base, err := url.Parse(c.baseURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid base URL: %w", err)
+	}
+
+	host := strings.ToLower(parsedURL.Host)
+	parts := strings.Split(strings.Trim(parsedURL.Path, "/"), "/")
+
+	switch {
+	case host == "medium.com" || host == "www.medium.com" || host == strings.ToLower(base.Host):
+		if len(parts) < 2 {
+			return "", fmt.Errorf("invalid Medium post URL format: %s", postURL)
+		}
+		if strings.HasPrefix(parts[0], "@") {
+			return parts[0], nil
+		}
+		return "pub:" + parts[0], nil
+	case strings.HasSuffix(host, ".medium.com"):
+		return "@" + strings.TrimSuffix(host, ".medium.com"), nil
+	default:
+		return "", fmt.Errorf("not a Medium URL: %s (posts on custom domains can be imported by username or from the account export)", postURL)
+	}
+}
+
+func (c *Client) get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/rss+xml, application/xml, text/html")
+	req.Header.Set("User-Agent", "GoREST-Blog-Importer/1.0")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return resp, nil
+}
+
+// feedPath returns the path of the feed of a user or publication.
+func feedPath(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if publication, ok := strings.CutPrefix(name, "pub:"); ok {
+		if publication == "" {
+			return "", fmt.Errorf("publication cannot be empty")
+		}
+		return "/feed/" + url.PathEscape(publication), nil
+	}
+
+	username := strings.TrimPrefix(name, "@")
+	if username == "" {
+		return "", fmt.Errorf("username cannot be empty")
+	}
+	return "/feed/@" + url.PathEscape(username), nil
+}
@@ -0,0 +1,84 @@
+package medium
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/nicolasbonnici/gorest-blog/importer/engines"
+)
+
+// Engine imports Medium posts from the RSS feed of their author or publication, which
+// only lists the latest posts, or from the account export archive.
+type Engine struct {
+	client *Client
+}
+
+func NewEngine() *Engine {
+	return NewEngineWithClient(NewClient())
+}
+
+func NewEngineWithClient(client *Client) *Engine {
+	return &Engine{
+		client: client,
+	}
+}
+
+func (e *Engine) Name() string {
+	return "medium"
+}
+
+// FetchByUsername fetches the posts of the feed of username, or of a publication given as
+// "pub:<name>".
+func (e *Engine) FetchByUsername(ctx context.Context, username string) ([]engines.Post, error) {
+	feed, err := e.client.GetFeed(ctx, username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch posts from Medium: %w", err)
+	}
+
+	return MapFeedItems(feed.Items), nil
+}
+
+func (e *Engine) FetchByID(ctx context.Context, id string) (*engines.Post, error) {
+	postURL, err := e.client.ResolvePostURL(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch post from Medium: %w", err)
+	}
+
+	return e.FetchByURL(ctx, postURL)
+}
+
+// FetchByURL fetches the post at url from the feed of its author or publication.
+func (e *Engine) FetchByURL(ctx context.Context, url string) (*engines.Post, error) {
+	id := postID(url)
+	if id == "" {
+		return nil, fmt.Errorf("could not extract post ID from URL: %s", url)
+	}
+
+	source, err := e.client.feedSource(url)
+	if err != nil {
+		return nil, err
+	}
+
+	feed, err := e.client.GetFeed(ctx, source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch post from Medium: %w", err)
+	}
+
+	for _, item := range feed.Items {
+		if post := MapFeedItem(item); post.ID == id {
+			return &post, nil
+		}
+	}
+
+	return nil, fmt.Errorf("post %s is not in the feed of %s: Medium feeds only list the latest posts, import older ones from the account export", id, source)
+}
+
+// FetchArchive reads the posts of a Medium account export ZIP.
+func (e *Engine) FetchArchive(ctx context.Context, r io.ReaderAt, size int64) ([]engines.Post, error) {
+	return ReadArchive(r, size)
+}
+
+func init() {
+	engines.Register(NewEngine())
+}
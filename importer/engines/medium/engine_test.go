@@ -0,0 +1,223 @@
+package medium
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nicolasbonnici/gorest-blog/importer/engines"
+)
+
+// newTestEngine returns an engine whose client talks to a stand-in Medium server serving
+// the recorded feed of @jane, also as the feed of the go-weekly publication, and the
+// /p/<id> redirects.
+func newTestEngine(t *testing.T) (*Engine, *httptest.Server) {
+	t.Helper()
+
+	feed, err := os.ReadFile(filepath.Join("testdata", "feed.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	serveFeed := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml; charset=UTF-8")
+		_, _ = w.Write(feed)
+	}
+	mux.HandleFunc("/feed/@jane", serveFeed)
+	mux.HandleFunc("/feed/go-weekly", serveFeed)
+	mux.HandleFunc("/p/abc123def456", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/@jane/hello-go-abc123def456", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/@jane/hello-go-abc123def456", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("<html></html>"))
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return NewEngineWithClient(NewClientWithBaseURL(server.URL)), server
+}
+
+func TestFetchByUsername(t *testing.T) {
+	engine, _ := newTestEngine(t)
+
+	posts, err := engine.FetchByUsername(context.Background(), "@jane")
+	if err != nil {
+		t.Fatalf("FetchByUsername: %v", err)
+	}
+	if len(posts) != 2 {
+		t.Fatalf("got %d posts, want 2", len(posts))
+	}
+
+	want := engines.Post{
+		ID:           "abc123def456",
+		Title:        "Hello Go",
+		Content:      `<figure><img alt="A gopher" src="https://cdn-images-1.medium.com/max/1024/1*gopher.png" /></figure><p>Go is <strong>simple</strong>.</p>`,
+		Slug:         "hello-go",
+		PublishedAt:  "2024-01-15T10:00:00Z",
+		UpdatedAt:    "2024-01-16T09:00:00Z",
+		URL:          "https://medium.com/@jane/hello-go-abc123def456",
+		SourceID:     "medium-abc123def456",
+		Tags:         []string{"golang", "programming"},
+		CoverImage:   "https://cdn-images-1.medium.com/max/1024/1*gopher.png",
+		CanonicalURL: "https://medium.com/@jane/hello-go-abc123def456",
+	}
+	if !reflect.DeepEqual(posts[0], want) {
+		t.Errorf("first post:\n got %+v\nwant %+v", posts[0], want)
+	}
+
+	second := posts[1]
+	if second.ID != "0fedcba98765" || second.Slug != "testing-with-httptest" || second.CoverImage != "" {
+		t.Errorf("second post: got id %q, slug %q, cover %q", second.ID, second.Slug, second.CoverImage)
+	}
+	if strings.Contains(second.Content, "_/stat") {
+		t.Errorf("second post content keeps the tracking pixel: %s", second.Content)
+	}
+}
+
+func TestFetchByUsernamePublication(t *testing.T) {
+	engine, _ := newTestEngine(t)
+
+	posts, err := engine.FetchByUsername(context.Background(), "pub:go-weekly")
+	if err != nil {
+		t.Fatalf("FetchByUsername: %v", err)
+	}
+	if len(posts) != 2 {
+		t.Errorf("got %d posts, want 2", len(posts))
+	}
+}
+
+func TestFetchByUsernameUnknownUser(t *testing.T) {
+	engine, _ := newTestEngine(t)
+
+	if _, err := engine.FetchByUsername(context.Background(), "nobody"); err == nil {
+		t.Fatal("FetchByUsername: expected an error for an unknown user")
+	}
+}
+
+func TestFetchByURL(t *testing.T) {
+	engine, server := newTestEngine(t)
+
+	post, err := engine.FetchByURL(context.Background(), server.URL+"/@jane/testing-with-httptest-0fedcba98765?source=home")
+	if err != nil {
+		t.Fatalf("FetchByURL: %v", err)
+	}
+	if post.ID != "0fedcba98765" || post.Title != "Testing with httptest" {
+		t.Errorf("got post %q %q, want 0fedcba98765 Testing with httptest", post.ID, post.Title)
+	}
+
+	if _, err := engine.FetchByURL(context.Background(), server.URL+"/@jane/older-post-111122223333"); err == nil {
+		t.Error("FetchByURL: expected an error for a post missing from the feed")
+	}
+	if _, err := engine.FetchByURL(context.Background(), "https://example.com/hello-go-abc123def456"); err == nil {
+		t.Error("FetchByURL: expected an error for a URL outside Medium")
+	}
+}
+
+func TestFetchByID(t *testing.T) {
+	engine, _ := newTestEngine(t)
+
+	post, err := engine.FetchByID(context.Background(), "abc123def456")
+	if err != nil {
+		t.Fatalf("FetchByID: %v", err)
+	}
+	if post.SourceID != "medium-abc123def456" || post.Title != "Hello Go" {
+		t.Errorf("got post %q %q, want medium-abc123def456 Hello Go", post.SourceID, post.Title)
+	}
+
+	if _, err := engine.FetchByID(context.Background(), "not-an-id"); err == nil {
+		t.Error("FetchByID: expected an error for an invalid id")
+	}
+}
+
+func TestFetchArchive(t *testing.T) {
+	engine, _ := newTestEngine(t)
+	archive := zipDir(t, filepath.Join("testdata", "export"))
+
+	posts, err := engine.FetchArchive(context.Background(), bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatalf("FetchArchive: %v", err)
+	}
+	if len(posts) != 2 {
+		t.Fatalf("got %d posts, want 2", len(posts))
+	}
+
+	published := posts[0]
+	if published.SourceID != "medium-abc123def456" {
+		t.Errorf("got source id %q, want the one of the feed item", published.SourceID)
+	}
+	if published.Title != "Hello Go" || published.Description != "A first look at the gopher" {
+		t.Errorf("got title %q, description %q", published.Title, published.Description)
+	}
+	if published.PublishedAt != "2024-01-15T10:00:00Z" {
+		t.Errorf("got published at %q, want 2024-01-15T10:00:00Z", published.PublishedAt)
+	}
+	if published.CanonicalURL != "https://medium.com/@jane/hello-go-abc123def456" || published.Slug != "hello-go" {
+		t.Errorf("got canonical URL %q, slug %q", published.CanonicalURL, published.Slug)
+	}
+	if published.CoverImage != "https://cdn-images-1.medium.com/max/800/1*gopher.png" {
+		t.Errorf("got cover image %q", published.CoverImage)
+	}
+	if strings.Contains(published.Content, "graf--title") || !strings.Contains(published.Content, "simple</strong>") {
+		t.Errorf("unexpected content: %s", published.Content)
+	}
+
+	draft := posts[1]
+	if draft.SourceID != "medium-0123456789ab" || draft.Title != "Notes on generics" {
+		t.Errorf("got draft %q %q", draft.SourceID, draft.Title)
+	}
+	if draft.PublishedAt != "" || draft.URL != "" {
+		t.Errorf("draft has published at %q and URL %q, want none", draft.PublishedAt, draft.URL)
+	}
+}
+
+func TestFetchArchiveRejectsOtherArchives(t *testing.T) {
+	engine, _ := newTestEngine(t)
+	archive := zipDir(t, filepath.Join("testdata", "export", "profile"))
+
+	if _, err := engine.FetchArchive(context.Background(), bytes.NewReader(archive), int64(len(archive))); err == nil {
+		t.Fatal("FetchArchive: expected an error for an archive without posts")
+	}
+}
+
+// zipDir returns a ZIP archive of the files under dir, named relative to it.
+func zipDir(t *testing.T, dir string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		f, err := w.Create(filepath.ToSlash(name))
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(content)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,143 @@
+package medium
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/nicolasbonnici/gorest-blog/importer/engines"
+)
+
+var (
+	// postIDPattern matches the hexadecimal id ending Medium post URLs and slugs.
+	postIDPattern = regexp.MustCompile(`^[0-9a-f]{8,16}$`)
+	// trackingPixel matches the view-tracking image Medium appends to feed content.
+	trackingPixel = regexp.MustCompile(`(?i)<img\b[^>]*\bsrc\s*=\s*["']https?://medium\.com/_/stat\?[^"']*["'][^>]*>`)
+	// leadingHeading matches a heading opening the content, Medium repeats the title there.
+	leadingHeading = regexp.MustCompile(`(?is)^\s*<h[1-4][^>]*>(.*?)</h[1-4]>`)
+	// titleHeading matches the title heading of the posts of the account export.
+	titleHeading = regexp.MustCompile(`(?is)<h[1-4][^>]*\bgraf--title\b[^>]*>.*?</h[1-4]>`)
+	// imageSource matches the src of <img> tags.
+	imageSource = regexp.MustCompile(`(?i)<img\b[^>]*\bsrc\s*=\s*["'](https?://[^"']+)["']`)
+	tag         = regexp.MustCompile(`<[^>]*>`)
+)
+
+// MapFeedItem maps a feed item, whose content is HTML.
+func MapFeedItem(item FeedItem) engines.Post {
+	postURL := stripQuery(item.Link)
+	id := postID(item.GUID)
+	if id == "" {
+		id = postID(postURL)
+	}
+
+	content := cleanContent(item.Content, item.Title)
+
+	return engines.Post{
+		ID:          id,
+		Title:       strings.TrimSpace(item.Title),
+		Content:     content,
+		Slug:        slugOf(postURL, id),
+		PublishedAt: formatTime(item.PubDate, time.RFC1123, time.RFC1123Z),
+		UpdatedAt:   formatTime(item.Updated, time.RFC3339),
+		URL:         postURL,
+		SourceID:    sourceID(id),
+		Tags:        item.Categories,
+
+		CoverImage:   firstImage(content),
+		CanonicalURL: postURL,
+	}
+}
+
+func MapFeedItems(items []FeedItem) []engines.Post {
+	posts := make([]engines.Post, 0, len(items))
+	for _, item := range items {
+		posts = append(posts, MapFeedItem(item))
+	}
+	return posts
+}
+
+// sourceID identifies a post whether it is imported from a feed or from the account
+// export, so that importing both updates the same post.
+func sourceID(id string) string {
+	if id == "" {
+		return ""
+	}
+	return fmt.Sprintf("medium-%s", id)
+}
+
+// postID returns the id ending a Medium post URL or slug, "" when there is none.
+func postID(value string) string {
+	value = strings.TrimSuffix(stripQuery(value), "/")
+	if i := strings.LastIndexAny(value, "/-_"); i >= 0 {
+		value = value[i+1:]
+	}
+	if !isPostID(value) {
+		return ""
+	}
+	return value
+}
+
+func isPostID(value string) bool {
+	return postIDPattern.MatchString(value)
+}
+
+// slugOf returns the slug of the post at postURL, without its id suffix.
+func slugOf(postURL, id string) string {
+	parsedURL, err := url.Parse(postURL)
+	if err != nil {
+		return ""
+	}
+	parts := strings.Split(strings.Trim(parsedURL.Path, "/"), "/")
+	return strings.TrimSuffix(parts[len(parts)-1], "-"+id)
+}
+
+func stripQuery(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	if i := strings.IndexAny(rawURL, "?#"); i >= 0 {
+		return rawURL[:i]
+	}
+	return rawURL
+}
+
+// cleanContent removes the tracking pixel of feed content and the heading repeating title.
+func cleanContent(content, title string) string {
+	content = trackingPixel.ReplaceAllString(content, "")
+	if loc := titleHeading.FindStringIndex(content); loc != nil {
+		content = content[:loc[0]] + content[loc[1]:]
+	}
+	if match := leadingHeading.FindStringSubmatch(content); match != nil && sameText(match[1], title) {
+		content = content[len(match[0]):]
+	}
+	return strings.TrimSpace(content)
+}
+
+func sameText(markup, text string) bool {
+	plain := html.UnescapeString(tag.ReplaceAllString(markup, ""))
+	return strings.EqualFold(strings.Join(strings.Fields(plain), " "), strings.Join(strings.Fields(text), " "))
+}
+
+// firstImage returns the URL of the first image of content, used as cover image.
+func firstImage(content string) string {
+	if match := imageSource.FindStringSubmatch(content); match != nil {
+		return html.UnescapeString(match[1])
+	}
+	return ""
+}
+
+// formatTime converts value, in one of layouts, to RFC3339. It returns "" when value is
+// empty or invalid.
+func formatTime(value string, layouts ...string) string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return ""
+	}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC().Format(time.RFC3339)
+		}
+	}
+	return ""
+}
@@ -9,4 +9,15 @@ type Post struct {
 	UpdatedAt   string
 	URL         string
 	SourceID    string
+	Tags        []string
+
+	// Description is the summary written on the source platform, used as the excerpt.
+	Description string
+	// ReadingTimeMinutes is the reading time computed by the source platform, if any.
+	ReadingTimeMinutes int
+	// CoverImage is the URL of the cover image on the source platform, if any.
+	CoverImage string
+	// CanonicalURL is the URL search engines should index for this content, usually the
+	// original article on the source platform.
+	CanonicalURL string
 }
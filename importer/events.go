@@ -0,0 +1,110 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/nicolasbonnici/gorest-blog/models"
+)
+
+// Types of the events streamed for an import job.
+const (
+	// EventSnapshot carries the job as stored, sent when a stream opens and, for jobs run
+	// by another replica, whenever the stored job changes.
+	EventSnapshot = "snapshot"
+	EventStart    = "start"
+	EventUpdate   = "update"
+	// EventOutcome reports the outcome of a processed post.
+	EventOutcome = "outcome"
+	EventError   = "error"
+	// EventFinish carries the finished job and ends the stream.
+	EventFinish = "finish"
+)
+
+// subscriberBuffer is the number of events kept for a slow subscriber before newer ones
+// are dropped.
+const subscriberBuffer = 64
+
+// Event is the progress of an import job, as streamed to its subscribers.
+type Event struct {
+	Type    string            `json:"type"`
+	Total   int               `json:"total,omitempty"`
+	Current int               `json:"current,omitempty"`
+	Message string            `json:"message,omitempty"`
+	Title   string            `json:"title,omitempty"`
+	Outcome string            `json:"outcome,omitempty"`
+	Error   string            `json:"error,omitempty"`
+	Job     *models.ImportJob `json:"job,omitempty"`
+}
+
+// write sends the event in the Server-Sent Events format and flushes it.
+func (e Event) write(w *bufio.Writer) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// Broadcaster fans the events of the jobs run by this instance out to their subscribers.
+type Broadcaster struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan Event]struct{}
+}
+
+func NewBroadcaster() *Broadcaster {
+	return &Broadcaster{subscribers: make(map[string]map[chan Event]struct{})}
+}
+
+// Subscribe returns the events of job id and a function to stop receiving them. The
+// channel is closed once the job finished, see Close.
+func (b *Broadcaster) Subscribe(id string) (<-chan Event, func()) {
+	events := make(chan Event, subscriberBuffer)
+
+	b.mu.Lock()
+	if b.subscribers[id] == nil {
+		b.subscribers[id] = make(map[chan Event]struct{})
+	}
+	b.subscribers[id][events] = struct{}{}
+	b.mu.Unlock()
+
+	return events, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[id][events]; ok {
+			delete(b.subscribers[id], events)
+			if len(b.subscribers[id]) == 0 {
+				delete(b.subscribers, id)
+			}
+			close(events)
+		}
+	}
+}
+
+// Publish sends event to the subscribers of job id. It never blocks: subscribers that
+// do not keep up miss events.
+func (b *Broadcaster) Publish(id string, event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for events := range b.subscribers[id] {
+		select {
+		case events <- event:
+		default:
+		}
+	}
+}
+
+// Close closes the channels of the subscribers of job id, once it finished.
+func (b *Broadcaster) Close(id string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for events := range b.subscribers[id] {
+		close(events)
+	}
+	delete(b.subscribers, id)
+}
@@ -1,22 +1,31 @@
 package importer
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	auth "github.com/nicolasbonnici/gorest-auth"
 	"github.com/nicolasbonnici/gorest-blog/importer/engines"
-	"github.com/nicolasbonnici/gorest/database"
+	"github.com/nicolasbonnici/gorest-blog/models"
+	"github.com/nicolasbonnici/gorest-blog/policy"
+	"github.com/nicolasbonnici/gorest-blog/types"
 )
 
+// ImportRequest is the body of POST /api/import/:engine. UserID is the author of the
+// imported posts: it defaults to the authenticated user, and only admins may import on
+// behalf of another user.
 type ImportRequest struct {
 	Username       string `json:"username,omitempty"`
 	ArticleURL     string `json:"url,omitempty"`
 	ArticleID      string `json:"id,omitempty"`
-	UserID         string `json:"user_id"`
+	UserID         string `json:"user_id,omitempty"`
 	UpdateExisting bool   `json:"update_existing,omitempty"`
 	DryRun         bool   `json:"dry_run,omitempty"`
+	DownloadMedia  bool   `json:"download_media,omitempty"`
 }
 
 type ImportResponse struct {
@@ -38,38 +47,22 @@ type EnginesResponse struct {
 	Engines []EngineInfo `json:"engines"`
 }
 
-func executeImport(ctx context.Context, db database.Database, engine string, req ImportRequest) (*ImportResult, error) {
-	if req.UserID == "" {
-		return nil, fmt.Errorf("user_id is required")
-	}
-
-	if req.Username == "" && req.ArticleURL == "" && req.ArticleID == "" {
-		return nil, fmt.Errorf("one of username, url, or id must be provided")
-	}
-
-	repo := NewRepository(db)
-	reporter := &NoOpProgressReporter{}
-	service := NewService(repo, reporter)
-
-	opts := ImportOptions{
-		Source:         engine,
-		UserID:         req.UserID,
-		Username:       req.Username,
-		ArticleURL:     req.ArticleURL,
-		ArticleID:      req.ArticleID,
-		UpdateExisting: req.UpdateExisting,
-		DryRun:         req.DryRun,
-	}
-
-	result, err := service.Import(ctx, opts)
-	if err != nil {
-		return nil, err
-	}
-
-	return result, nil
+// JobsResponse is a page of import jobs, newest first.
+type JobsResponse struct {
+	Jobs   []models.ImportJob `json:"jobs"`
+	Total  int                `json:"total"`
+	Limit  int                `json:"limit"`
+	Offset int                `json:"offset"`
 }
 
-func handleImport(db database.Database) fiber.Handler {
+const (
+	defaultJobsLimit = 20
+	maxJobsLimit     = 100
+)
+
+// handleImport queues an import on behalf of the authenticated user and answers 202 with
+// the queued job, whose progress is reported by the jobs endpoints.
+func handleImport(queue *Queue, pol *policy.Policy) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		engine := c.Params("engine")
 		if engine == "" {
@@ -79,13 +72,6 @@ func handleImport(db database.Database) fiber.Handler {
 			})
 		}
 
-		if _, ok := engines.Get(engine); !ok {
-			return c.Status(fiber.StatusBadRequest).JSON(ImportResponse{
-				Success: false,
-				Message: fmt.Sprintf("unknown engine: %s (available: %v)", engine, engines.List()),
-			})
-		}
-
 		var req ImportRequest
 		if err := c.BodyParser(&req); err != nil {
 			return c.Status(fiber.StatusBadRequest).JSON(ImportResponse{
@@ -94,10 +80,23 @@ func handleImport(db database.Database) fiber.Handler {
 			})
 		}
 
-		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Minute)
-		defer cancel()
+		if user := auth.GetAuthenticatedUser(c); user != nil && req.UserID == "" {
+			req.UserID = user.UserID
+		}
+		if ferr := pol.Authorize(c, &req.UserID); ferr != nil {
+			return c.Status(ferr.Code).JSON(ImportResponse{
+				Success: false,
+				Message: ferr.Message,
+			})
+		}
 
-		result, err := executeImport(ctx, db, engine, req)
+		job, err := queue.Enqueue(c.Context(), engine, req)
+		if errors.Is(err, ErrInvalidJob) {
+			return c.Status(fiber.StatusBadRequest).JSON(ImportResponse{
+				Success: false,
+				Message: err.Error(),
+			})
+		}
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(ImportResponse{
 				Success: false,
@@ -105,21 +104,220 @@ func handleImport(db database.Database) fiber.Handler {
 			})
 		}
 
-		errorMessages := make([]string, 0, len(result.Errors))
-		for _, err := range result.Errors {
-			errorMessages = append(errorMessages, err.Error())
+		c.Location("/api/import/jobs/" + job.Id)
+		return c.Status(fiber.StatusAccepted).JSON(job)
+	}
+}
+
+// handleListJobs lists the import jobs of the authenticated user, or every job for admins,
+// optionally filtered by ?status= and, for admins, ?user_id=, paginated with ?limit= and
+// ?offset=.
+func handleListJobs(queue *Queue, pol *policy.Policy) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user := auth.GetAuthenticatedUser(c)
+		if user == nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Authentication required"})
+		}
+		userID := c.Query("user_id")
+		if !pol.IsAdmin(c) {
+			userID = user.UserID
+		}
+
+		status := types.ImportJobStatus(c.Query("status"))
+		if status != "" && !status.IsValid() {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Query parameter status must be queued, running, succeeded, failed or cancelled",
+			})
+		}
+
+		limit := c.QueryInt("limit", defaultJobsLimit)
+		if limit <= 0 || limit > maxJobsLimit {
+			limit = defaultJobsLimit
+		}
+		offset := c.QueryInt("offset", 0)
+		if offset < 0 {
+			offset = 0
+		}
+
+		jobs, total, err := queue.Jobs().List(c.Context(), status, userID, limit, offset)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.JSON(JobsResponse{
+			Jobs:   jobs,
+			Total:  total,
+			Limit:  limit,
+			Offset: offset,
+		})
+	}
+}
+
+// authorizedJob returns job id when the authenticated user owns it or is an admin.
+func authorizedJob(c *fiber.Ctx, queue *Queue, pol *policy.Policy, id string) (*models.ImportJob, *fiber.Error) {
+	if auth.GetAuthenticatedUser(c) == nil {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Authentication required")
+	}
+
+	job, err := queue.Jobs().Get(c.Context(), id)
+	if errors.Is(err, ErrJobNotFound) {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Import job not found")
+	}
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	if ferr := pol.Authorize(c, &job.UserId); ferr != nil {
+		return nil, ferr
+	}
+	return job, nil
+}
+
+func handleGetJob(queue *Queue, pol *policy.Policy) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		job, ferr := authorizedJob(c, queue, pol, c.Params("id"))
+		if ferr != nil {
+			return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+		}
+
+		return c.JSON(job)
+	}
+}
+
+// keepAliveInterval is how often an idle event stream sends a comment, which keeps proxies
+// from closing it and detects disconnected clients.
+const keepAliveInterval = 15 * time.Second
+
+// handleJobEvents streams the progress of a job as Server-Sent Events: a snapshot of the
+// job, then its start, update, outcome and error events, and a finish event carrying the
+// finished job, which ends the stream. Jobs run by another replica are followed by polling
+// their row, and only report snapshots. Only the owner of the job and admins may follow it.
+func handleJobEvents(queue *Queue, pol *policy.Policy) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := c.Params("id")
+
+		// Subscribe before reading the job, so that no event is missed in between.
+		events, unsubscribe := queue.Events().Subscribe(id)
+
+		job, ferr := authorizedJob(c, queue, pol, id)
+		if ferr != nil {
+			unsubscribe()
+			return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
 		}
 
-		return c.JSON(ImportResponse{
-			Success:      result.Failed == 0,
-			Message:      result.String(),
-			TotalFetched: result.TotalFetched,
-			Created:      result.Created,
-			Updated:      result.Updated,
-			Skipped:      result.Skipped,
-			Failed:       result.Failed,
-			Errors:       errorMessages,
+		c.Set("Content-Type", "text/event-stream")
+		c.Set("Cache-Control", "no-cache")
+		c.Set("Connection", "keep-alive")
+		c.Set("X-Accel-Buffering", "no")
+
+		c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			defer unsubscribe()
+			streamJobEvents(w, queue, job, events)
 		})
+
+		return nil
+	}
+}
+
+// streamJobEvents writes the events of job to w until it finishes or the client leaves.
+func streamJobEvents(w *bufio.Writer, queue *Queue, job *models.ImportJob, events <-chan Event) {
+	ctx := context.Background()
+
+	if types.ImportJobStatus(job.Status).IsFinished() {
+		_ = Event{Type: EventFinish, Job: job}.write(w)
+		return
+	}
+	if err := (Event{Type: EventSnapshot, Job: job}).write(w); err != nil {
+		return
+	}
+
+	poll := time.NewTicker(queue.pollInterval)
+	defer poll.Stop()
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	live := false
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				// The job finished on this instance.
+				if finished, err := queue.Jobs().Get(ctx, job.Id); err == nil {
+					_ = Event{Type: EventFinish, Job: finished}.write(w)
+				}
+				return
+			}
+			live = true
+			if err := event.write(w); err != nil {
+				return
+			}
+
+		case <-poll.C:
+			current, err := queue.Jobs().Get(ctx, job.Id)
+			if err != nil {
+				continue
+			}
+			if queue.isStale(current) {
+				// Its worker is gone: fail it so that the stream ends with it.
+				queue.failStale(ctx)
+				if current, err = queue.Jobs().Get(ctx, job.Id); err != nil {
+					continue
+				}
+			}
+			if types.ImportJobStatus(current.Status).IsFinished() {
+				_ = Event{Type: EventFinish, Job: current}.write(w)
+				return
+			}
+			// Events of jobs run here are streamed as they happen; snapshots only follow
+			// jobs run by other replicas.
+			if !live && !sameTime(current.UpdatedAt, job.UpdatedAt) {
+				if err := (Event{Type: EventSnapshot, Job: current}).write(w); err != nil {
+					return
+				}
+			}
+			job = current
+			live = false
+
+		case <-queue.stopped:
+			return
+
+		case <-keepAlive.C:
+			if _, err := w.WriteString(": keep-alive\n\n"); err != nil {
+				return
+			}
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// handleCancelJob cancels a queued or running job of the authenticated user, or any job for
+// admins, and returns it. Finished jobs answer 409.
+func handleCancelJob(queue *Queue, pol *policy.Policy) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if _, ferr := authorizedJob(c, queue, pol, c.Params("id")); ferr != nil {
+			return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+		}
+
+		job, err := queue.Cancel(c.Context(), c.Params("id"))
+		switch {
+		case errors.Is(err, ErrJobNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Import job not found"})
+		case errors.Is(err, ErrJobFinished):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Import job already finished"})
+		case err != nil:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.JSON(job)
 	}
 }
 
@@ -137,7 +335,14 @@ func handleListEngines() fiber.Handler {
 	}
 }
 
-func RegisterRoutes(router fiber.Router, db database.Database) {
-	router.Post("/api/import/:engine", handleImport(db))
+// RegisterRoutes registers the import endpoints. Imports are queued on queue, whose
+// workers must be started with Queue.Run. Importing and following jobs require an
+// authenticated user; pol lets admins act on every job and other users on their own.
+func RegisterRoutes(router fiber.Router, queue *Queue, pol *policy.Policy) {
 	router.Get("/api/import/engines", handleListEngines())
+	router.Get("/api/import/jobs", handleListJobs(queue, pol))
+	router.Get("/api/import/jobs/:id", handleGetJob(queue, pol))
+	router.Get("/api/import/jobs/:id/events", handleJobEvents(queue, pol))
+	router.Delete("/api/import/jobs/:id", handleCancelJob(queue, pol))
+	router.Post("/api/import/:engine", handleImport(queue, pol))
 }
@@ -0,0 +1,326 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nicolasbonnici/gorest-blog/models"
+	"github.com/nicolasbonnici/gorest-blog/types"
+	"github.com/nicolasbonnici/gorest/database"
+)
+
+var (
+	ErrJobNotFound = errors.New("import job not found")
+	ErrJobFinished = errors.New("import job already finished")
+)
+
+const jobColumns = `id, engine, status, user_id, username, article_url, article_id,
+		       update_existing, dry_run, download_media,
+		       total_count, processed_count, created_count, updated_count, skipped_count, failed_count,
+		       message, errors, started_at, finished_at, updated_at, created_at`
+
+// JobStore persists import jobs in the import_job table.
+type JobStore struct {
+	db database.Database
+}
+
+func NewJobStore(db database.Database) *JobStore {
+	return &JobStore{db: db}
+}
+
+// Create queues job and fills its id, status and timestamps.
+func (s *JobStore) Create(ctx context.Context, job *models.ImportJob) error {
+	query := `
+		INSERT INTO import_job (engine, user_id, username, article_url, article_id,
+		                        update_existing, dry_run, download_media)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING ` + jobColumns
+
+	rows, err := s.db.Query(ctx, query,
+		job.Engine,
+		job.UserId,
+		job.Username,
+		job.ArticleUrl,
+		job.ArticleId,
+		job.UpdateExisting,
+		job.DryRun,
+		job.DownloadMedia,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create import job: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	if !rows.Next() {
+		return fmt.Errorf("failed to create import job: no row returned")
+	}
+	return scanJob(rows, job)
+}
+
+// Get returns the job id, or ErrJobNotFound.
+func (s *JobStore) Get(ctx context.Context, id string) (*models.ImportJob, error) {
+	return s.one(ctx, "SELECT "+jobColumns+" FROM import_job WHERE id::text = $1", "failed to find import job", id)
+}
+
+// List returns a page of the jobs, newest first, and their total count. status and
+// userID restrict the jobs listed when not empty.
+func (s *JobStore) List(ctx context.Context, status types.ImportJobStatus, userID string, limit, offset int) ([]models.ImportJob, int, error) {
+	where := "($1 = '' OR status = $1) AND ($2 = '' OR user_id::text = $2)"
+
+	var total int
+	if err := s.db.QueryRow(ctx, "SELECT COUNT(*) FROM import_job WHERE "+where, status.String(), userID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count import jobs: %w", err)
+	}
+
+	query := "SELECT " + jobColumns + " FROM import_job WHERE " + where + `
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3 OFFSET $4`
+
+	rows, err := s.db.Query(ctx, query, status.String(), userID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list import jobs: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	jobs := make([]models.ImportJob, 0)
+	for rows.Next() {
+		var job models.ImportJob
+		if err := scanJob(rows, &job); err != nil {
+			return nil, 0, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, total, nil
+}
+
+// Claim marks the oldest queued job as running and returns it, nil when no job is queued.
+// The job is locked with FOR UPDATE SKIP LOCKED, so concurrent workers never claim the
+// same job.
+func (s *JobStore) Claim(ctx context.Context) (*models.ImportJob, error) {
+	query := `
+		UPDATE import_job
+		SET status = $1, started_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = (
+			SELECT id FROM import_job
+			WHERE status = $2
+			ORDER BY created_at, id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + jobColumns
+
+	job, err := s.one(ctx, query, "failed to claim import job", types.ImportJobStatusRunning.String(), types.ImportJobStatusQueued.String())
+	if errors.Is(err, ErrJobNotFound) {
+		return nil, nil
+	}
+	return job, err
+}
+
+// Heartbeat records that the worker of job id is still alive and returns the job status,
+// which lets workers notice jobs cancelled or failed meanwhile.
+func (s *JobStore) Heartbeat(ctx context.Context, id string) (types.ImportJobStatus, error) {
+	return s.progress(ctx, id, "")
+}
+
+// FailStale marks as failed the running jobs whose row was not updated since before: their
+// worker stopped without recording their outcome, e.g. because its process was killed. It
+// returns the ids of the failed jobs.
+func (s *JobStore) FailStale(ctx context.Context, before time.Time) ([]string, error) {
+	query := `
+		UPDATE import_job
+		SET status = $1, message = $2, finished_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE status = $3 AND COALESCE(updated_at, started_at, created_at) < $4
+		RETURNING id`
+
+	rows, err := s.db.Query(ctx, query,
+		types.ImportJobStatusFailed.String(),
+		"Import interrupted: its worker stopped responding",
+		types.ImportJobStatusRunning.String(),
+		before,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fail stale import jobs: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan stale import job: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// Start records the number of posts a running job is about to import.
+func (s *JobStore) Start(ctx context.Context, id string, total int, message string) (types.ImportJobStatus, error) {
+	return s.progress(ctx, id, "total_count = $2, message = $3", total, message)
+}
+
+// Progress records the number of posts a running job processed so far.
+func (s *JobStore) Progress(ctx context.Context, id string, processed int, message string) (types.ImportJobStatus, error) {
+	return s.progress(ctx, id, "processed_count = $2, message = $3", processed, message)
+}
+
+// AddError appends message to the errors of a job.
+func (s *JobStore) AddError(ctx context.Context, id string, message string) (types.ImportJobStatus, error) {
+	return s.progress(ctx, id, "errors = array_append(errors, $2)", message)
+}
+
+// outcomeColumns maps the outcome of a post to the job column counting it.
+var outcomeColumns = map[string]string{
+	OutcomeCreated: "created_count",
+	OutcomeUpdated: "updated_count",
+	OutcomeSkipped: "skipped_count",
+	OutcomeFailed:  "failed_count",
+}
+
+// CountOutcome counts a post processed by a running job with outcome.
+func (s *JobStore) CountOutcome(ctx context.Context, id string, outcome string) (types.ImportJobStatus, error) {
+	column, ok := outcomeColumns[outcome]
+	if !ok {
+		return "", fmt.Errorf("unknown import outcome: %s", outcome)
+	}
+	return s.progress(ctx, id, fmt.Sprintf("%[1]s = %[1]s + 1", column))
+}
+
+// progress applies set, which may be empty, to job id and returns its status, which lets
+// workers notice jobs cancelled meanwhile.
+func (s *JobStore) progress(ctx context.Context, id, set string, args ...any) (types.ImportJobStatus, error) {
+	if set != "" {
+		set += ", "
+	}
+	query := "UPDATE import_job SET " + set + "updated_at = CURRENT_TIMESTAMP WHERE id = $1 RETURNING status"
+
+	rows, err := s.db.Query(ctx, query, append([]any{id}, args...)...)
+	if err != nil {
+		return "", fmt.Errorf("failed to update import job: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	if !rows.Next() {
+		return "", ErrJobNotFound
+	}
+
+	var status string
+	if err := rows.Scan(&status); err != nil {
+		return "", fmt.Errorf("failed to scan import job status: %w", err)
+	}
+	return types.ImportJobStatus(status), nil
+}
+
+// Finish records the outcome of a job. A job cancelled while it ran, or failed as stale,
+// keeps its status, with the counts of the work done before it stopped.
+func (s *JobStore) Finish(ctx context.Context, id string, status types.ImportJobStatus, result *ImportResult, message string) error {
+	if result == nil {
+		result = &ImportResult{}
+	}
+
+	query := `
+		UPDATE import_job
+		SET status = CASE WHEN status IN ($2, $11) THEN status ELSE $3 END,
+		    total_count = $4, processed_count = $5, created_count = $6, updated_count = $7,
+		    skipped_count = $8, failed_count = $9, message = $10,
+		    finished_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`
+
+	processed := result.Created + result.Updated + result.Skipped + result.Failed
+	if _, err := s.db.Exec(ctx, query,
+		id,
+		types.ImportJobStatusCancelled.String(),
+		status.String(),
+		result.TotalFetched,
+		processed,
+		result.Created,
+		result.Updated,
+		result.Skipped,
+		result.Failed,
+		message,
+		types.ImportJobStatusFailed.String(),
+	); err != nil {
+		return fmt.Errorf("failed to finish import job: %w", err)
+	}
+
+	return nil
+}
+
+// Cancel marks a queued or running job as cancelled and returns it. ErrJobFinished is
+// returned when the job already finished.
+func (s *JobStore) Cancel(ctx context.Context, id string) (*models.ImportJob, error) {
+	query := `
+		UPDATE import_job
+		SET status = $2,
+		    finished_at = CASE WHEN status = $3 THEN CURRENT_TIMESTAMP ELSE finished_at END,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id::text = $1 AND status IN ($3, $4)
+		RETURNING ` + jobColumns
+
+	job, err := s.one(ctx, query, "failed to cancel import job", id,
+		types.ImportJobStatusCancelled.String(),
+		types.ImportJobStatusQueued.String(),
+		types.ImportJobStatusRunning.String(),
+	)
+	if !errors.Is(err, ErrJobNotFound) {
+		return job, err
+	}
+
+	if _, err := s.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	return nil, ErrJobFinished
+}
+
+// one runs query, returning jobColumns, and scans its only row. ErrJobNotFound is returned
+// when there is none.
+func (s *JobStore) one(ctx context.Context, query, failure string, args ...any) (*models.ImportJob, error) {
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", failure, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	if !rows.Next() {
+		return nil, ErrJobNotFound
+	}
+
+	var job models.ImportJob
+	if err := scanJob(rows, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func scanJob(rows interface{ Scan(dest ...any) error }, job *models.ImportJob) error {
+	if err := rows.Scan(
+		&job.Id,
+		&job.Engine,
+		&job.Status,
+		&job.UserId,
+		&job.Username,
+		&job.ArticleUrl,
+		&job.ArticleId,
+		&job.UpdateExisting,
+		&job.DryRun,
+		&job.DownloadMedia,
+		&job.TotalCount,
+		&job.ProcessedCount,
+		&job.CreatedCount,
+		&job.UpdatedCount,
+		&job.SkippedCount,
+		&job.FailedCount,
+		&job.Message,
+		&job.Errors,
+		&job.StartedAt,
+		&job.FinishedAt,
+		&job.UpdatedAt,
+		&job.CreatedAt,
+	); err != nil {
+		return fmt.Errorf("failed to scan import job: %w", err)
+	}
+	return nil
+}
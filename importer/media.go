@@ -0,0 +1,64 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/nicolasbonnici/gorest-blog/models"
+)
+
+var (
+	// markdownImage matches the URL of ![alt](url "title") images.
+	markdownImage = regexp.MustCompile(`!\[[^\]]*\]\(\s*<?(https?://[^)\s>]+)>?`)
+	// htmlImage matches the src of <img> tags written as inline HTML.
+	htmlImage = regexp.MustCompile(`(?i)<img\b[^>]*\bsrc\s*=\s*["'](https?://[^"']+)["']`)
+)
+
+// downloadMedia copies the cover image and the inline images of post into the media
+// storage, then points postModel at the local copies. Images that cannot be downloaded
+// are reported and keep their remote URL: they do not fail the import.
+func (s *Service) downloadMedia(ctx context.Context, post Post, postModel *models.Post, userID string) {
+	downloaded := make(map[string]*models.Media)
+	download := func(url string) *models.Media {
+		if m, ok := downloaded[url]; ok {
+			return m
+		}
+		m, err := s.media.Download(ctx, userID, url)
+		if err != nil {
+			s.reporter.Error(fmt.Errorf("failed to download image of '%s': %w", post.Title, err))
+		}
+		downloaded[url] = m
+		return m
+	}
+
+	if post.CoverImage != "" {
+		if m := download(post.CoverImage); m != nil {
+			postModel.CoverImageId = &m.Id
+		}
+	}
+
+	content := postModel.Content
+	for _, url := range imageURLs(content) {
+		if m := download(url); m != nil {
+			content = strings.ReplaceAll(content, url, s.media.URL(m))
+		}
+	}
+	postModel.Content = content
+}
+
+// imageURLs lists the distinct remote image URLs of a markdown document, in order.
+func imageURLs(content string) []string {
+	seen := make(map[string]bool)
+	var urls []string
+	for _, pattern := range []*regexp.Regexp{markdownImage, htmlImage} {
+		for _, match := range pattern.FindAllStringSubmatch(content, -1) {
+			if url := match[1]; !seen[url] {
+				seen[url] = true
+				urls = append(urls, url)
+			}
+		}
+	}
+	return urls
+}
@@ -5,6 +5,8 @@ import (
 	"github.com/nicolasbonnici/gorest/plugin"
 
 	_ "github.com/nicolasbonnici/gorest-blog/importer/engines/devto"
+	_ "github.com/nicolasbonnici/gorest-blog/importer/engines/hashnode"
+	_ "github.com/nicolasbonnici/gorest-blog/importer/engines/medium"
 )
 
 type Plugin struct{}
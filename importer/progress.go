@@ -1,8 +1,79 @@
 package importer
 
+import (
+	"context"
+	"log"
+
+	"github.com/nicolasbonnici/gorest-blog/types"
+)
+
 type NoOpProgressReporter struct{}
 
 func (r *NoOpProgressReporter) Start(total int, message string)    {}
 func (r *NoOpProgressReporter) Update(current int, message string) {}
 func (r *NoOpProgressReporter) Finish(message string)              {}
 func (r *NoOpProgressReporter) Error(err error)                    {}
+
+// JobProgressReporter writes the progress of an import to its job row and publishes it
+// to the subscribers of the job. When the job turns out to be cancelled, cancel is called
+// to stop the import.
+type JobProgressReporter struct {
+	ctx    context.Context
+	jobs   *JobStore
+	events *Broadcaster
+	id     string
+	cancel context.CancelFunc
+}
+
+func newJobReporter(ctx context.Context, jobs *JobStore, events *Broadcaster, id string, cancel context.CancelFunc) *JobProgressReporter {
+	return &JobProgressReporter{
+		ctx:    ctx,
+		jobs:   jobs,
+		events: events,
+		id:     id,
+		cancel: cancel,
+	}
+}
+
+func (r *JobProgressReporter) Start(total int, message string) {
+	r.events.Publish(r.id, Event{Type: EventStart, Total: total, Message: message})
+	r.check(r.jobs.Start(r.ctx, r.id, total, message))
+}
+
+func (r *JobProgressReporter) Update(current int, message string) {
+	r.events.Publish(r.id, Event{Type: EventUpdate, Current: current, Message: message})
+	r.check(r.jobs.Progress(r.ctx, r.id, current, message))
+}
+
+// Finish is a no-op: the queue records the outcome of the job once the import returns.
+func (r *JobProgressReporter) Finish(message string) {}
+
+func (r *JobProgressReporter) Error(err error) {
+	r.events.Publish(r.id, Event{Type: EventError, Error: err.Error()})
+	r.check(r.jobs.AddError(r.ctx, r.id, err.Error()))
+}
+
+// Outcome counts the outcome of a post on the job row, so that it is up to date while the
+// import runs.
+func (r *JobProgressReporter) Outcome(current int, post Post, outcome string, err error) {
+	event := Event{Type: EventOutcome, Current: current, Title: post.Title, Outcome: outcome}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	r.events.Publish(r.id, event)
+	r.check(r.jobs.CountOutcome(r.ctx, r.id, outcome))
+}
+
+// check cancels the import when the job was cancelled, possibly by another replica.
+// Failing to record progress does not stop the import.
+func (r *JobProgressReporter) check(status types.ImportJobStatus, err error) {
+	if err != nil {
+		if r.ctx.Err() == nil {
+			log.Printf("[importer] job %s: %v", r.id, err)
+		}
+		return
+	}
+	if status == types.ImportJobStatusCancelled {
+		r.cancel()
+	}
+}
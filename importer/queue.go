@@ -0,0 +1,318 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/nicolasbonnici/gorest-blog/importer/engines"
+	"github.com/nicolasbonnici/gorest-blog/models"
+	"github.com/nicolasbonnici/gorest-blog/types"
+	"github.com/nicolasbonnici/gorest/database"
+)
+
+const (
+	// DefaultWorkers is the number of imports run concurrently by a Queue.
+	DefaultWorkers = 2
+	// DefaultPollInterval is how often idle workers look for jobs queued by other replicas.
+	DefaultPollInterval = 5 * time.Second
+	// DefaultHeartbeatInterval is how often a worker touches the row of the job it runs.
+	DefaultHeartbeatInterval = 30 * time.Second
+	// DefaultStaleAfter is how long a running job may go without heartbeat before it is
+	// considered abandoned by its worker and marked as failed.
+	DefaultStaleAfter = 5 * time.Minute
+)
+
+// ErrInvalidJob wraps the reasons an import request is rejected before being queued.
+var ErrInvalidJob = errors.New("invalid import job")
+
+// Queue runs import jobs in the background. Jobs are persisted in the import_job table,
+// so their progress outlives the request that queued them and several replicas can share
+// the work.
+type Queue struct {
+	jobs         *JobStore
+	db           database.Database
+	media        MediaDownloader
+	workers      int
+	pollInterval time.Duration
+	heartbeat    time.Duration
+	staleAfter   time.Duration
+	wake         chan struct{}
+	events       *Broadcaster
+	stopped      chan struct{}
+
+	mu      sync.Mutex
+	running map[string]context.CancelFunc
+
+	// OnImport, when set, is called with the ids of the posts created or updated by each
+	// job, including jobs that failed or were cancelled after changing some posts.
+	OnImport func(ids []string)
+}
+
+// NewQueue returns a queue running workers imports at a time. media may be nil to disable
+// downloads, in which case jobs asking for download_media are rejected.
+func NewQueue(db database.Database, media MediaDownloader, workers int) *Queue {
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	return &Queue{
+		jobs:         NewJobStore(db),
+		db:           db,
+		media:        media,
+		workers:      workers,
+		pollInterval: DefaultPollInterval,
+		heartbeat:    DefaultHeartbeatInterval,
+		staleAfter:   DefaultStaleAfter,
+		wake:         make(chan struct{}, 1),
+		events:       NewBroadcaster(),
+		stopped:      make(chan struct{}),
+		running:      make(map[string]context.CancelFunc),
+	}
+}
+
+// Jobs returns the store of the queued jobs.
+func (q *Queue) Jobs() *JobStore {
+	return q.jobs
+}
+
+// Events returns the broadcaster of the events of the jobs run by this instance.
+func (q *Queue) Events() *Broadcaster {
+	return q.events
+}
+
+// Enqueue validates req and queues an import from engine. Invalid requests are reported
+// with an error wrapping ErrInvalidJob.
+func (q *Queue) Enqueue(ctx context.Context, engine string, req ImportRequest) (*models.ImportJob, error) {
+	if _, ok := engines.Get(engine); !ok {
+		return nil, fmt.Errorf("%w: unknown engine: %s (available: %v)", ErrInvalidJob, engine, engines.List())
+	}
+	if req.UserID == "" {
+		return nil, fmt.Errorf("%w: user_id is required", ErrInvalidJob)
+	}
+	if req.Username == "" && req.ArticleURL == "" && req.ArticleID == "" {
+		return nil, fmt.Errorf("%w: one of username, url, or id must be provided", ErrInvalidJob)
+	}
+	if req.DownloadMedia && q.media == nil {
+		return nil, fmt.Errorf("%w: download_media is not enabled on this server", ErrInvalidJob)
+	}
+
+	exists, err := NewRepository(q.db).UserExists(ctx, req.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate user: %w", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("%w: user_id '%s' does not exist", ErrInvalidJob, req.UserID)
+	}
+
+	job := &models.ImportJob{
+		Engine:         engine,
+		UserId:         req.UserID,
+		Username:       optional(req.Username),
+		ArticleUrl:     optional(req.ArticleURL),
+		ArticleId:      optional(req.ArticleID),
+		UpdateExisting: req.UpdateExisting,
+		DryRun:         req.DryRun,
+		DownloadMedia:  req.DownloadMedia,
+	}
+	if err := q.jobs.Create(ctx, job); err != nil {
+		return nil, err
+	}
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+
+	return job, nil
+}
+
+// Cancel cancels job id. A queued job never runs; a running job stops after the post it
+// is importing, keeping the posts already imported.
+func (q *Queue) Cancel(ctx context.Context, id string) (*models.ImportJob, error) {
+	job, err := q.jobs.Cancel(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	q.mu.Lock()
+	if cancel, ok := q.running[job.Id]; ok {
+		cancel()
+	}
+	q.mu.Unlock()
+
+	return job, nil
+}
+
+// Run executes queued jobs until ctx is cancelled. Jobs interrupted by the cancellation
+// are marked as failed, and the event streams of the queue are ended. Running jobs left
+// without heartbeat for staleAfter, by this or another instance, are marked as failed when
+// a worker starts and before it claims a job.
+func (q *Queue) Run(ctx context.Context) {
+	defer close(q.stopped)
+
+	var wg sync.WaitGroup
+	for i := 0; i < q.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+// work claims and executes jobs one at a time, waiting for new ones when the queue is empty.
+func (q *Queue) work(ctx context.Context) {
+	ticker := time.NewTicker(q.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		q.failStale(ctx)
+
+		job, err := q.jobs.Claim(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("[importer] failed to claim import job: %v", err)
+		}
+		if job != nil {
+			q.execute(ctx, job)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// failStale marks as failed the running jobs whose worker stopped sending heartbeats, and
+// ends their local event streams.
+func (q *Queue) failStale(ctx context.Context) {
+	ids, err := q.jobs.FailStale(ctx, time.Now().Add(-q.staleAfter))
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("[importer] %v", err)
+		}
+		return
+	}
+	for _, id := range ids {
+		log.Printf("[importer] job %s: no heartbeat for %s, marked as failed", id, q.staleAfter)
+		q.events.Close(id)
+	}
+}
+
+// isStale reports whether job is running without heartbeat for staleAfter.
+func (q *Queue) isStale(job *models.ImportJob) bool {
+	if types.ImportJobStatus(job.Status) != types.ImportJobStatusRunning || job.UpdatedAt == nil {
+		return false
+	}
+	return time.Since(*job.UpdatedAt) > q.staleAfter
+}
+
+// keepAlive touches the row of job id every heartbeat interval until ctx is done, so that
+// it is not taken for abandoned. The job is stopped when it is no longer running, e.g.
+// when it was cancelled through another instance.
+func (q *Queue) keepAlive(ctx context.Context, id string, cancel context.CancelFunc) {
+	ticker := time.NewTicker(q.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			status, err := q.jobs.Heartbeat(ctx, id)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("[importer] job %s: %v", id, err)
+				}
+				continue
+			}
+			if status != types.ImportJobStatusRunning {
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// execute runs a claimed job and records its outcome.
+func (q *Queue) execute(ctx context.Context, job *models.ImportJob) {
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	q.mu.Lock()
+	q.running[job.Id] = cancel
+	q.mu.Unlock()
+	defer func() {
+		q.mu.Lock()
+		delete(q.running, job.Id)
+		q.mu.Unlock()
+	}()
+
+	go q.keepAlive(jobCtx, job.Id, cancel)
+
+	service := NewService(NewRepository(q.db), newJobReporter(jobCtx, q.jobs, q.events, job.Id, cancel))
+	if q.media != nil {
+		service.WithMedia(q.media)
+	}
+
+	result, err := service.Import(jobCtx, ImportOptions{
+		Source:         job.Engine,
+		UserID:         job.UserId,
+		Username:       deref(job.Username),
+		ArticleURL:     deref(job.ArticleUrl),
+		ArticleID:      deref(job.ArticleId),
+		UpdateExisting: job.UpdateExisting,
+		DryRun:         job.DryRun,
+		DownloadMedia:  job.DownloadMedia,
+	})
+
+	status := types.ImportJobStatusSucceeded
+	message := ""
+	switch {
+	case ctx.Err() != nil:
+		status = types.ImportJobStatusFailed
+		message = "Import interrupted: the server is shutting down"
+	case jobCtx.Err() != nil:
+		status = types.ImportJobStatusCancelled
+		message = "Import cancelled"
+	case err != nil:
+		status = types.ImportJobStatusFailed
+		message = fmt.Sprintf("Import failed: %v", err)
+	default:
+		message = result.String()
+	}
+	if result != nil && status != types.ImportJobStatusSucceeded {
+		message = fmt.Sprintf("%s (%s)", message, result.String())
+	}
+
+	if result != nil && len(result.PostIDs) > 0 && q.OnImport != nil {
+		q.OnImport(result.PostIDs)
+	}
+
+	// The job context may be done already, the outcome is recorded regardless.
+	if err := q.jobs.Finish(context.WithoutCancel(ctx), job.Id, status, result, message); err != nil {
+		log.Printf("[importer] job %s: %v", job.Id, err)
+	}
+	q.events.Close(job.Id)
+}
+
+func optional(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+func deref(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 
 	"github.com/nicolasbonnici/gorest-blog/models"
+	"github.com/nicolasbonnici/gorest-blog/slug"
+	"github.com/nicolasbonnici/gorest-blog/taxonomy"
 	"github.com/nicolasbonnici/gorest/crud"
 	"github.com/nicolasbonnici/gorest/database"
 )
@@ -12,81 +14,144 @@ import (
 type Repository interface {
 	Create(ctx context.Context, post *models.Post) error
 	Update(ctx context.Context, id string, post *models.Post) error
-	FindByTitle(ctx context.Context, title string) (*models.Post, error)
 	FindByID(ctx context.Context, id string) (*models.Post, error)
+	// FindUntracked returns the post of userID without import source matching an article,
+	// nil when there is none. It adopts posts imported before sources were tracked, matched
+	// by canonical URL, then by slug. Trashed posts are matched too.
+	FindUntracked(ctx context.Context, userID, canonicalURL, postSlug string) (*models.Post, error)
+	// FindSource returns the import source of the article sourceID of engine imported by
+	// userID, nil when the user never imported the article.
+	FindSource(ctx context.Context, userID, engine, sourceID string) (*models.PostImportSource, error)
+	// SaveSource records that source.PostId was imported from the article of source.
+	SaveSource(ctx context.Context, source *models.PostImportSource) error
 	UserExists(ctx context.Context, userID string) (bool, error)
+	SetTags(ctx context.Context, postID string, tags []string) error
 }
 
 type PostgresRepository struct {
-	crud *crud.CRUD[models.Post]
-	db   database.Database
+	crud     *crud.CRUD[models.Post]
+	db       database.Database
+	taxonomy *taxonomy.Store
+	slugs    *slug.Registry
 }
 
 func NewRepository(db database.Database) Repository {
 	return &PostgresRepository{
-		crud: crud.New[models.Post](db),
-		db:   db,
+		crud:     crud.New[models.Post](db),
+		db:       db,
+		taxonomy: taxonomy.NewStore(db),
+		// Imported slugs are kept globally unique, which also satisfies the per-author scope.
+		slugs: slug.NewRegistry(db, slug.ScopeGlobal),
 	}
 }
 
 func (r *PostgresRepository) Create(ctx context.Context, post *models.Post) error {
+	postSlug, err := r.slugs.Unique(ctx, post.Slug, post.UserId, "")
+	if err != nil {
+		return err
+	}
+	post.Slug = postSlug
+	post.SlugScope = string(r.slugs.Scope())
+
 	// Use explicit SQL to ensure published_at is properly handled
 	query := `
-		INSERT INTO post (user_id, slug, status, title, content, published_at, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, CURRENT_TIMESTAMP)
+		INSERT INTO post (user_id, slug, slug_scope, status, title, content, excerpt, excerpt_override,
+		                  word_count, reading_time_minutes, cover_image_id, canonical_url, published_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, CURRENT_TIMESTAMP)
 		RETURNING id, created_at`
 
-	rows, err := r.db.Query(ctx, query,
+	err = r.db.QueryRow(ctx, query,
 		post.UserId,
 		post.Slug,
+		post.SlugScope,
 		post.Status,
 		post.Title,
 		post.Content,
+		post.Excerpt,
+		post.ExcerptOverride,
+		post.WordCount,
+		post.ReadingTimeMinutes,
+		post.CoverImageId,
+		post.CanonicalUrl,
 		post.PublishedAt,
-	)
+	).Scan(&post.Id, &post.CreatedAt)
 	if err != nil {
 		return fmt.Errorf("failed to create post: %w", err)
 	}
-	defer func() { _ = rows.Close() }()
-
-	if rows.Next() {
-		if err := rows.Scan(&post.Id, &post.CreatedAt); err != nil {
-			return fmt.Errorf("failed to scan created post: %w", err)
-		}
-	}
 
 	return nil
 }
 
 func (r *PostgresRepository) Update(ctx context.Context, id string, post *models.Post) error {
+	existing, err := r.crud.GetByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to find post: %w", err)
+	}
+
+	postSlug, err := r.slugs.Unique(ctx, post.Slug, post.UserId, id)
+	if err != nil {
+		return err
+	}
+	post.Slug = postSlug
+	post.SlugScope = string(r.slugs.Scope())
+
 	// Use explicit SQL to ensure published_at is properly handled
 	query := `
 		UPDATE post
-		SET user_id = $1, slug = $2, status = $3, title = $4, content = $5,
-		    published_at = $6, updated_at = CURRENT_TIMESTAMP
-		WHERE id = $7`
+		SET user_id = $1, slug = $2, slug_scope = $3, status = $4, title = $5, content = $6,
+		    excerpt = $7, excerpt_override = $8, word_count = $9, reading_time_minutes = $10,
+		    cover_image_id = COALESCE($11, cover_image_id), canonical_url = $12,
+		    published_at = $13, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $14`
 
-	_, err := r.db.Query(ctx, query,
+	if _, err := r.db.Exec(ctx, query,
 		post.UserId,
 		post.Slug,
+		post.SlugScope,
 		post.Status,
 		post.Title,
 		post.Content,
+		post.Excerpt,
+		post.ExcerptOverride,
+		post.WordCount,
+		post.ReadingTimeMinutes,
+		post.CoverImageId,
+		post.CanonicalUrl,
 		post.PublishedAt,
 		id,
-	)
-	if err != nil {
+	); err != nil {
 		return fmt.Errorf("failed to update post: %w", err)
 	}
 
+	if existing.Slug != post.Slug {
+		return r.slugs.RecordChange(ctx, id, existing.UserId, existing.Slug)
+	}
+
 	return nil
 }
 
-func (r *PostgresRepository) FindByTitle(ctx context.Context, title string) (*models.Post, error) {
-	query := "SELECT id, user_id, slug, status, title, content, published_at, updated_at, created_at FROM post WHERE title = $1 LIMIT 1"
-	var post models.Post
+func (r *PostgresRepository) FindByID(ctx context.Context, id string) (*models.Post, error) {
+	post, err := r.crud.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find post: %w", err)
+	}
+	return post, nil
+}
+
+func (r *PostgresRepository) FindUntracked(ctx context.Context, userID, canonicalURL, postSlug string) (*models.Post, error) {
+	// Posts imported before this table existed may have no canonical URL: they are recognized
+	// by their slug. Titles are not matched, so a post written in the blog is never taken for
+	// an article sharing its title.
+	query := `
+		SELECT p.id
+		FROM post p
+		WHERE p.user_id = $1
+		  AND ((p.canonical_url = $2 AND $2 <> '') OR p.slug = $3)
+		  AND NOT EXISTS (SELECT 1 FROM post_import_source s WHERE s.post_id = p.id)
+		ORDER BY CASE WHEN p.canonical_url = $2 THEN 0 ELSE 1 END, p.created_at
+		LIMIT 1`
 
-	rows, err := r.db.Query(ctx, query, title)
+	rows, err := r.db.Query(ctx, query, userID, canonicalURL, postSlug)
 	if err != nil {
 		return nil, fmt.Errorf("query failed: %w", err)
 	}
@@ -96,29 +161,77 @@ func (r *PostgresRepository) FindByTitle(ctx context.Context, title string) (*mo
 		return nil, nil
 	}
 
+	var id string
+	if err := rows.Scan(&id); err != nil {
+		return nil, fmt.Errorf("scan failed: %w", err)
+	}
+
+	return r.FindByID(ctx, id)
+}
+
+func (r *PostgresRepository) FindSource(ctx context.Context, userID, engine, sourceID string) (*models.PostImportSource, error) {
+	query := `
+		SELECT id, post_id, user_id, engine, source_id, source_url, content_hash, imported_at, created_at
+		FROM post_import_source
+		WHERE user_id = $1 AND engine = $2 AND source_id = $3`
+
+	rows, err := r.db.Query(ctx, query, userID, engine, sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find import source: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	if !rows.Next() {
+		return nil, nil
+	}
+
+	var source models.PostImportSource
 	if err := rows.Scan(
-		&post.Id,
-		&post.UserId,
-		&post.Slug,
-		&post.Status,
-		&post.Title,
-		&post.Content,
-		&post.PublishedAt,
-		&post.UpdatedAt,
-		&post.CreatedAt,
+		&source.Id,
+		&source.PostId,
+		&source.UserId,
+		&source.Engine,
+		&source.SourceId,
+		&source.SourceUrl,
+		&source.ContentHash,
+		&source.ImportedAt,
+		&source.CreatedAt,
 	); err != nil {
-		return nil, fmt.Errorf("scan failed: %w", err)
+		return nil, fmt.Errorf("failed to scan import source: %w", err)
 	}
 
-	return &post, nil
+	return &source, nil
 }
 
-func (r *PostgresRepository) FindByID(ctx context.Context, id string) (*models.Post, error) {
-	post, err := r.crud.GetByID(ctx, id)
+func (r *PostgresRepository) SaveSource(ctx context.Context, source *models.PostImportSource) error {
+	query := `
+		INSERT INTO post_import_source (post_id, user_id, engine, source_id, source_url, content_hash, imported_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		ON CONFLICT (user_id, engine, source_id) DO UPDATE
+		SET post_id = EXCLUDED.post_id, source_url = EXCLUDED.source_url,
+		    content_hash = EXCLUDED.content_hash, imported_at = EXCLUDED.imported_at
+		RETURNING id, imported_at, created_at`
+
+	rows, err := r.db.Query(ctx, query,
+		source.PostId,
+		source.UserId,
+		source.Engine,
+		source.SourceId,
+		source.SourceUrl,
+		source.ContentHash,
+	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to find post: %w", err)
+		return fmt.Errorf("failed to save import source: %w", err)
 	}
-	return post, nil
+	defer func() { _ = rows.Close() }()
+
+	if rows.Next() {
+		if err := rows.Scan(&source.Id, &source.ImportedAt, &source.CreatedAt); err != nil {
+			return fmt.Errorf("failed to scan import source: %w", err)
+		}
+	}
+
+	return nil
 }
 
 func (r *PostgresRepository) UserExists(ctx context.Context, userID string) (bool, error) {
@@ -141,3 +254,10 @@ func (r *PostgresRepository) UserExists(ctx context.Context, userID string) (boo
 
 	return exists, nil
 }
+
+func (r *PostgresRepository) SetTags(ctx context.Context, postID string, tags []string) error {
+	if _, err := r.taxonomy.SetPostTags(ctx, postID, tags, true); err != nil {
+		return fmt.Errorf("failed to set tags: %w", err)
+	}
+	return nil
+}
@@ -3,18 +3,21 @@ package importer
 import (
 	"context"
 	"fmt"
-	"regexp"
+	"os"
 	"strings"
 	"time"
 
-	"github.com/nicolasbonnici/gorest-blog/models"
+	"github.com/nicolasbonnici/gorest-blog/hooks"
 	"github.com/nicolasbonnici/gorest-blog/importer/engines"
+	"github.com/nicolasbonnici/gorest-blog/models"
+	"github.com/nicolasbonnici/gorest-blog/slug"
 	"github.com/nicolasbonnici/gorest-blog/types"
 )
 
 type Service struct {
 	repository Repository
 	reporter   ProgressReporter
+	media      MediaDownloader
 }
 
 func NewService(repo Repository, reporter ProgressReporter) *Service {
@@ -27,6 +30,12 @@ func NewService(repo Repository, reporter ProgressReporter) *Service {
 	}
 }
 
+// WithMedia sets the downloader used by imports with DownloadMedia.
+func (s *Service) WithMedia(media MediaDownloader) *Service {
+	s.media = media
+	return s
+}
+
 func (s *Service) Import(ctx context.Context, opts ImportOptions) (*ImportResult, error) {
 	engine, ok := engines.Get(opts.Source)
 	if !ok {
@@ -37,6 +46,10 @@ func (s *Service) Import(ctx context.Context, opts ImportOptions) (*ImportResult
 		return nil, fmt.Errorf("user_id is required")
 	}
 
+	if opts.DownloadMedia && s.media == nil {
+		return nil, fmt.Errorf("media download requested but no media storage is configured")
+	}
+
 	// Validate that the user exists before attempting to import
 	userExists, err := s.repository.UserExists(ctx, opts.UserID)
 	if err != nil {
@@ -65,8 +78,13 @@ func (s *Service) Import(ctx context.Context, opts ImportOptions) (*ImportResult
 			return nil, fmt.Errorf("failed to fetch post by ID: %w", err)
 		}
 		posts = []Post{*post}
+	} else if opts.ArchivePath != "" {
+		posts, err = fetchArchive(ctx, engine, opts.ArchivePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
 	} else {
-		return nil, fmt.Errorf("one of username, url, or id must be provided")
+		return nil, fmt.Errorf("one of username, url, id, or archive must be provided")
 	}
 
 	result := &ImportResult{
@@ -83,6 +101,13 @@ func (s *Service) Import(ctx context.Context, opts ImportOptions) (*ImportResult
 			s.reporter.Update(i+1, fmt.Sprintf("Processing: %s", post.Title))
 		}
 
+		// Stop between posts, so that a cancelled import does not leave a post half imported.
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		default:
+		}
+
 		action, err := s.importPost(ctx, post, opts, result)
 		if err != nil {
 			result.Failed++
@@ -90,23 +115,19 @@ func (s *Service) Import(ctx context.Context, opts ImportOptions) (*ImportResult
 			if s.reporter != nil {
 				s.reporter.Error(err)
 			}
+			s.reportOutcome(i+1, post, OutcomeFailed, err)
 			continue
 		}
 
 		switch action {
-		case "created":
+		case OutcomeCreated:
 			result.Created++
-		case "updated":
+		case OutcomeUpdated:
 			result.Updated++
-		case "skipped":
+		case OutcomeSkipped:
 			result.Skipped++
 		}
-
-		select {
-		case <-ctx.Done():
-			return result, ctx.Err()
-		default:
-		}
+		s.reportOutcome(i+1, post, action, nil)
 	}
 
 	if s.reporter != nil {
@@ -116,36 +137,119 @@ func (s *Service) Import(ctx context.Context, opts ImportOptions) (*ImportResult
 	return result, nil
 }
 
+// fetchArchive reads the posts of the account export at path, when engine supports them.
+func fetchArchive(ctx context.Context, engine engines.Engine, path string) ([]Post, error) {
+	archiveEngine, ok := engine.(engines.ArchiveEngine)
+	if !ok {
+		return nil, fmt.Errorf("engine %s does not import archives", engine.Name())
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	return archiveEngine.FetchArchive(ctx, file, info.Size())
+}
+
+// reportOutcome tells the reporter the outcome of a post when it is an OutcomeReporter.
+func (s *Service) reportOutcome(current int, post Post, outcome string, err error) {
+	if reporter, ok := s.reporter.(OutcomeReporter); ok {
+		reporter.Outcome(current, post, outcome, err)
+	}
+}
+
+// importPost creates or updates the post of an article. Articles are identified by the
+// importing user, the engine and their source id, so renaming an article updates its post; an article whose
+// content hash did not change since its last import is skipped. Posts imported before
+// sources were tracked are adopted by canonical URL or slug.
+//
+// An article whose post is in the trash is skipped: it is neither updated nor imported
+// again. Restoring the post resumes its updates; purging it lets the article be imported
+// as a new post.
 func (s *Service) importPost(ctx context.Context, post Post, opts ImportOptions, result *ImportResult) (string, error) {
+	sourceID := sourceKey(post)
+	if sourceID == "" {
+		return "", fmt.Errorf("the article has no source id")
+	}
+	hash := contentHash(post)
 	postModel := s.postToModel(post, opts.UserID)
 
+	source, err := s.repository.FindSource(ctx, opts.UserID, opts.Source, sourceID)
+	if err != nil {
+		return "", err
+	}
+
+	var existing *models.Post
+	if source != nil {
+		existing, err = s.repository.FindByID(ctx, source.PostId)
+	} else {
+		existing, err = s.repository.FindUntracked(ctx, opts.UserID, originalURL(post), postModel.Slug)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if existing != nil && (existing.DeletedAt != nil || !opts.UpdateExisting || (source != nil && source.ContentHash == hash)) {
+		return OutcomeSkipped, nil
+	}
+
 	if opts.DryRun {
-		existing, err := s.repository.FindByTitle(ctx, post.Title)
-		if err == nil && existing != nil {
-			if opts.UpdateExisting {
-				return "updated", nil
-			}
-			return "skipped", nil
+		if existing != nil {
+			return OutcomeUpdated, nil
 		}
-		return "created", nil
+		return OutcomeCreated, nil
 	}
 
-	existing, err := s.repository.FindByTitle(ctx, post.Title)
-	if err == nil && existing != nil {
-		if opts.UpdateExisting {
-			if err := s.repository.Update(ctx, existing.Id, &postModel); err != nil {
-				return "", fmt.Errorf("update failed: %w", err)
-			}
-			return "updated", nil
-		}
-		return "skipped", nil
+	if opts.DownloadMedia {
+		s.downloadMedia(ctx, post, &postModel, opts.UserID)
 	}
 
-	if err := s.repository.Create(ctx, &postModel); err != nil {
+	action := OutcomeCreated
+	if existing != nil {
+		if err := s.repository.Update(ctx, existing.Id, &postModel); err != nil {
+			return "", fmt.Errorf("update failed: %w", err)
+		}
+		postModel.Id = existing.Id
+		action = OutcomeUpdated
+	} else if err := s.repository.Create(ctx, &postModel); err != nil {
 		return "", fmt.Errorf("create failed: %w", err)
 	}
+	result.PostIDs = append(result.PostIDs, postModel.Id)
 
-	return "created", nil
+	if err := s.repository.SetTags(ctx, postModel.Id, post.Tags); err != nil {
+		return "", err
+	}
+
+	record := &models.PostImportSource{
+		PostId:      postModel.Id,
+		UserId:      opts.UserID,
+		Engine:      opts.Source,
+		SourceId:    sourceID,
+		ContentHash: hash,
+	}
+	if post.URL != "" {
+		record.SourceUrl = &post.URL
+	}
+	if err := s.repository.SaveSource(ctx, record); err != nil {
+		return "", err
+	}
+
+	return action, nil
+}
+
+// originalURL returns the canonical URL of post, falling back to its URL on the source platform.
+func originalURL(post Post) string {
+	if canonicalURL := strings.TrimSpace(post.CanonicalURL); canonicalURL != "" {
+		return canonicalURL
+	}
+	return strings.TrimSpace(post.URL)
 }
 
 func (s *Service) postToModel(post Post, userID string) models.Post {
@@ -162,42 +266,30 @@ func (s *Service) postToModel(post Post, userID string) models.Post {
 	}
 
 	// Use slug from post, or generate from title if empty
-	slug := post.Slug
-	if slug == "" {
-		slug = slugify(post.Title)
+	postSlug := slug.Make(post.Slug)
+	if postSlug == "" {
+		postSlug = slug.Make(post.Title)
 	}
 
 	postModel := models.Post{
 		Title:       post.Title,
 		Content:     post.Content,
-		Slug:        slug,
+		Slug:        postSlug,
 		Status:      string(status),
 		PublishedAt: publishedAt,
 		UserId:      &userID,
 	}
+	if description := strings.TrimSpace(post.Description); description != "" {
+		postModel.ExcerptOverride = &description
+	}
+	if canonicalURL := strings.TrimSpace(post.CanonicalURL); canonicalURL != "" {
+		postModel.CanonicalUrl = &canonicalURL
+	}
 
-	return postModel
-}
-
-// slugify converts a string into a URL-friendly slug
-func slugify(s string) string {
-	// Convert to lowercase
-	s = strings.ToLower(s)
-
-	// Replace spaces and underscores with hyphens
-	s = strings.ReplaceAll(s, " ", "-")
-	s = strings.ReplaceAll(s, "_", "-")
-
-	// Remove all non-alphanumeric characters except hyphens
-	reg := regexp.MustCompile(`[^a-z0-9-]+`)
-	s = reg.ReplaceAllString(s, "")
-
-	// Remove multiple consecutive hyphens
-	reg = regexp.MustCompile(`-+`)
-	s = reg.ReplaceAllString(s, "-")
-
-	// Trim hyphens from start and end
-	s = strings.Trim(s, "-")
+	hooks.SetContentStats(&postModel)
+	if post.ReadingTimeMinutes > 0 {
+		postModel.ReadingTimeMinutes = post.ReadingTimeMinutes
+	}
 
-	return s
+	return postModel
 }
@@ -0,0 +1,255 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nicolasbonnici/gorest-blog/importer/engines"
+	"github.com/nicolasbonnici/gorest-blog/models"
+)
+
+// stubEngine serves a fixed list of articles.
+type stubEngine struct {
+	posts []Post
+}
+
+func (e *stubEngine) Name() string { return "stub" }
+
+func (e *stubEngine) FetchByUsername(ctx context.Context, username string) ([]Post, error) {
+	return e.posts, nil
+}
+
+func (e *stubEngine) FetchByID(ctx context.Context, id string) (*Post, error) {
+	return nil, errors.New("not supported")
+}
+
+func (e *stubEngine) FetchByURL(ctx context.Context, url string) (*Post, error) {
+	return nil, errors.New("not supported")
+}
+
+// memoryRepository is an in-memory Repository.
+type memoryRepository struct {
+	posts   map[string]*models.Post
+	sources map[string]*models.PostImportSource
+	created []string
+	updated []string
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{
+		posts:   make(map[string]*models.Post),
+		sources: make(map[string]*models.PostImportSource),
+	}
+}
+
+func (r *memoryRepository) Create(ctx context.Context, post *models.Post) error {
+	post.Id = fmt.Sprintf("post-%d", len(r.posts)+1)
+	stored := *post
+	r.posts[post.Id] = &stored
+	r.created = append(r.created, post.Id)
+	return nil
+}
+
+func (r *memoryRepository) Update(ctx context.Context, id string, post *models.Post) error {
+	stored := *post
+	stored.Id = id
+	stored.DeletedAt = r.posts[id].DeletedAt
+	r.posts[id] = &stored
+	r.updated = append(r.updated, id)
+	return nil
+}
+
+func (r *memoryRepository) FindByID(ctx context.Context, id string) (*models.Post, error) {
+	post, ok := r.posts[id]
+	if !ok {
+		return nil, fmt.Errorf("failed to find post: %s", id)
+	}
+	return post, nil
+}
+
+func (r *memoryRepository) FindUntracked(ctx context.Context, userID, canonicalURL, postSlug string) (*models.Post, error) {
+	tracked := make(map[string]bool)
+	for _, source := range r.sources {
+		tracked[source.PostId] = true
+	}
+
+	var bySlug *models.Post
+	for _, post := range r.posts {
+		if tracked[post.Id] || post.UserId == nil || *post.UserId != userID {
+			continue
+		}
+		switch {
+		case canonicalURL != "" && post.CanonicalUrl != nil && *post.CanonicalUrl == canonicalURL:
+			return post, nil
+		case post.Slug == postSlug:
+			bySlug = post
+		}
+	}
+	return bySlug, nil
+}
+
+func (r *memoryRepository) FindSource(ctx context.Context, userID, engine, sourceID string) (*models.PostImportSource, error) {
+	return r.sources[userID+"/"+engine+"/"+sourceID], nil
+}
+
+func (r *memoryRepository) SaveSource(ctx context.Context, source *models.PostImportSource) error {
+	r.sources[source.UserId+"/"+source.Engine+"/"+source.SourceId] = source
+	return nil
+}
+
+func (r *memoryRepository) UserExists(ctx context.Context, userID string) (bool, error) {
+	return true, nil
+}
+
+func (r *memoryRepository) SetTags(ctx context.Context, postID string, tags []string) error {
+	return nil
+}
+
+func (r *memoryRepository) add(post models.Post) *models.Post {
+	r.posts[post.Id] = &post
+	return &post
+}
+
+const testUserID = "user-1"
+
+func runImport(t *testing.T, repo *memoryRepository, update bool, posts ...Post) *ImportResult {
+	t.Helper()
+	return runImportAs(t, repo, testUserID, update, posts...)
+}
+
+func runImportAs(t *testing.T, repo *memoryRepository, userID string, update bool, posts ...Post) *ImportResult {
+	t.Helper()
+
+	engines.Register(&stubEngine{posts: posts})
+	t.Cleanup(func() { engines.Clear() })
+
+	result, err := NewService(repo, nil).Import(context.Background(), ImportOptions{
+		Source:         "stub",
+		UserID:         userID,
+		Username:       "jane",
+		UpdateExisting: update,
+	})
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	return result
+}
+
+func article(id, title, slug string) Post {
+	return Post{
+		SourceID: id,
+		Title:    title,
+		Slug:     slug,
+		Content:  "Content of " + title,
+		URL:      "https://example.com/" + slug,
+	}
+}
+
+func TestImportTracksSources(t *testing.T) {
+	repo := newMemoryRepository()
+	hello := article("1", "Hello", "hello")
+
+	result := runImport(t, repo, true, hello)
+	if result.Created != 1 || !reflect.DeepEqual(result.PostIDs, repo.created) {
+		t.Fatalf("first import: got %+v", result)
+	}
+
+	result = runImport(t, repo, true, hello)
+	if result.Skipped != 1 || len(result.PostIDs) != 0 {
+		t.Errorf("unchanged article: got %+v, want it skipped", result)
+	}
+
+	hello.Title = "Hello again"
+	result = runImport(t, repo, true, hello)
+	if result.Updated != 1 || len(repo.posts) != 1 {
+		t.Errorf("renamed article: got %+v and %d posts, want its post updated", result, len(repo.posts))
+	}
+}
+
+func TestImportKeepsSourcesPerUser(t *testing.T) {
+	repo := newMemoryRepository()
+	hello := article("1", "Hello", "hello")
+
+	runImportAs(t, repo, testUserID, true, hello)
+	first := repo.created[0]
+
+	hello.Content = "Changed content"
+	result := runImportAs(t, repo, "user-2", true, hello)
+	if result.Created != 1 || result.Updated != 0 || len(repo.posts) != 2 {
+		t.Fatalf("got %+v and %d posts, want a new post for the second user", result, len(repo.posts))
+	}
+
+	if owner := repo.posts[first].UserId; owner == nil || *owner != testUserID {
+		t.Errorf("the first user's post now belongs to %v", owner)
+	}
+	if content := repo.posts[first].Content; content != "Content of Hello" {
+		t.Errorf("the first user's post content was overwritten with %q", content)
+	}
+	if source := repo.sources["user-2/stub/1"]; source == nil || source.PostId == first {
+		t.Errorf("the second user's source is %+v, want it to point to their own post", source)
+	}
+}
+
+func TestImportAdoptsUntrackedPosts(t *testing.T) {
+	userID := testUserID
+	otherID := "user-2"
+	canonicalURL := "https://example.com/hello"
+
+	tests := []struct {
+		name     string
+		existing models.Post
+		adopted  bool
+	}{
+		{"by slug", models.Post{Id: "legacy", UserId: &userID, Slug: "hello", Title: "Old title"}, true},
+		{"by canonical URL", models.Post{Id: "legacy", UserId: &userID, Slug: "old-slug", Title: "Old title", CanonicalUrl: &canonicalURL}, true},
+		{"not by title", models.Post{Id: "legacy", UserId: &userID, Slug: "native-post", Title: "Hello"}, false},
+		{"of another user", models.Post{Id: "legacy", UserId: &otherID, Slug: "hello", Title: "Hello"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMemoryRepository()
+			repo.add(tt.existing)
+
+			result := runImport(t, repo, true, article("1", "Hello", "hello"))
+			if adopted := result.Updated == 1 && result.Created == 0; adopted != tt.adopted {
+				t.Errorf("got %+v, want adopted %v", result, tt.adopted)
+			}
+			if tt.adopted && repo.sources[testUserID+"/stub/1"].PostId != "legacy" {
+				t.Errorf("the source of the article points to %q, want the legacy post", repo.sources[testUserID+"/stub/1"].PostId)
+			}
+		})
+	}
+}
+
+func TestImportSkipsTrashedPosts(t *testing.T) {
+	userID := testUserID
+	deletedAt := time.Now()
+
+	t.Run("tracked", func(t *testing.T) {
+		repo := newMemoryRepository()
+		hello := article("1", "Hello", "hello")
+		runImport(t, repo, true, hello)
+		repo.posts[repo.created[0]].DeletedAt = &deletedAt
+
+		hello.Content = "Changed content"
+		result := runImport(t, repo, true, hello)
+		if result.Skipped != 1 || len(repo.posts) != 1 || len(repo.updated) != 0 {
+			t.Errorf("got %+v, %d posts and %d updates, want the trashed post left alone", result, len(repo.posts), len(repo.updated))
+		}
+	})
+
+	t.Run("untracked", func(t *testing.T) {
+		repo := newMemoryRepository()
+		repo.add(models.Post{Id: "legacy", UserId: &userID, Slug: "hello", Title: "Hello", DeletedAt: &deletedAt})
+
+		result := runImport(t, repo, true, article("1", "Hello", "hello"))
+		if result.Skipped != 1 || len(repo.posts) != 1 {
+			t.Errorf("got %+v and %d posts, want the trashed post left alone", result, len(repo.posts))
+		}
+	})
+}
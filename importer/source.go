@@ -0,0 +1,42 @@
+package importer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+)
+
+// sourceKey returns the identity of post on its platform: the source id given by the
+// engine, falling back to its id and then its URL.
+func sourceKey(post Post) string {
+	for _, key := range []string{post.SourceID, post.ID, post.URL} {
+		if key = strings.TrimSpace(key); key != "" {
+			return key
+		}
+	}
+	return ""
+}
+
+// contentHash returns a digest of the fields of post copied into the blog, so that an
+// article whose hash did not change since its last import can be skipped.
+func contentHash(post Post) string {
+	tags := append([]string(nil), post.Tags...)
+	sort.Strings(tags)
+
+	h := sha256.New()
+	for _, field := range []string{
+		post.Title,
+		post.Content,
+		post.Slug,
+		post.PublishedAt,
+		post.Description,
+		post.CoverImage,
+		post.CanonicalURL,
+		strings.Join(tags, ","),
+	} {
+		h.Write([]byte(field))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
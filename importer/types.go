@@ -1,9 +1,11 @@
 package importer
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/nicolasbonnici/gorest-blog/importer/engines"
+	"github.com/nicolasbonnici/gorest-blog/models"
 )
 
 type Post = engines.Post
@@ -16,6 +18,14 @@ type ImportOptions struct {
 	Username       string
 	ArticleURL     string
 	ArticleID      string
+
+	// ArchivePath is the path of an account export archive to import, for engines
+	// implementing engines.ArchiveEngine.
+	ArchivePath string
+
+	// DownloadMedia copies the cover image and inline images of imported posts into the
+	// blog's media storage. It requires a MediaDownloader, see Service.WithMedia.
+	DownloadMedia bool
 }
 
 type ImportResult struct {
@@ -25,6 +35,8 @@ type ImportResult struct {
 	Skipped      int
 	Failed       int
 	Errors       []error
+	// PostIDs lists the posts created or updated by the import.
+	PostIDs []string
 }
 
 func (r *ImportResult) Success() int {
@@ -44,3 +56,24 @@ type ProgressReporter interface {
 	Finish(message string)
 	Error(err error)
 }
+
+// Outcomes of importing a post.
+const (
+	OutcomeCreated = "created"
+	OutcomeUpdated = "updated"
+	OutcomeSkipped = "skipped"
+	OutcomeFailed  = "failed"
+)
+
+// OutcomeReporter may be implemented by a ProgressReporter to be told the outcome of
+// each post once it is processed. err is set when outcome is OutcomeFailed.
+type OutcomeReporter interface {
+	Outcome(current int, post Post, outcome string, err error)
+}
+
+// MediaDownloader copies remote images into the blog's media storage. It is implemented
+// by media.Store.
+type MediaDownloader interface {
+	Download(ctx context.Context, userID, url string) (*models.Media, error)
+	URL(m *models.Media) string
+}
@@ -3,9 +3,11 @@ package blog
 import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/nicolasbonnici/gorest-blog/importer"
-	"github.com/nicolasbonnici/gorest/database"
+	"github.com/nicolasbonnici/gorest-blog/policy"
 )
 
-func RegisterImporterRoutes(app *fiber.App, db database.Database) {
-	importer.RegisterRoutes(app, db)
+// RegisterImporterRoutes registers the import endpoints, queueing imports on queue and
+// authorizing callers with pol.
+func RegisterImporterRoutes(app *fiber.App, queue *importer.Queue, pol *policy.Policy) {
+	importer.RegisterRoutes(app, queue, pol)
 }
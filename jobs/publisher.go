@@ -0,0 +1,99 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/nicolasbonnici/gorest-blog/types"
+	"github.com/nicolasbonnici/gorest/database"
+)
+
+const (
+	DefaultPublishInterval = 30 * time.Second
+	publishBatchSize       = 100
+)
+
+// Publisher flips scheduled posts to published once their published_at is due.
+//
+// Due rows are claimed with SELECT ... FOR UPDATE SKIP LOCKED inside a single UPDATE,
+// so several replicas can run a Publisher against the same database without
+// publishing a post twice or blocking each other.
+type Publisher struct {
+	db       database.Database
+	interval time.Duration
+
+	// OnPublish, when set, is called with the ids of the posts published by each run.
+	OnPublish func(ids []string)
+}
+
+func NewPublisher(db database.Database, interval time.Duration) *Publisher {
+	if interval <= 0 {
+		interval = DefaultPublishInterval
+	}
+	return &Publisher{
+		db:       db,
+		interval: interval,
+	}
+}
+
+// Run publishes due posts every interval until ctx is cancelled.
+func (p *Publisher) Run(ctx context.Context) {
+	runEvery(ctx, "publisher", p.interval, func(ctx context.Context) error {
+		_, err := p.PublishDue(ctx)
+		return err
+	})
+}
+
+// PublishDue publishes every scheduled post whose published_at is in the past and
+// returns their ids.
+func (p *Publisher) PublishDue(ctx context.Context) ([]string, error) {
+	query := `
+		UPDATE post SET status = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id IN (
+			SELECT id FROM post
+			WHERE status = $2 AND published_at <= CURRENT_TIMESTAMP AND deleted_at IS NULL
+			ORDER BY published_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id`
+
+	published := make([]string, 0)
+	for {
+		rows, err := p.db.Query(ctx, query, types.PostStatusPublished.String(), types.PostStatusScheduled.String(), publishBatchSize)
+		if err != nil {
+			return published, fmt.Errorf("failed to publish scheduled posts: %w", err)
+		}
+
+		batch := 0
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				_ = rows.Close()
+				return published, fmt.Errorf("failed to scan published post: %w", err)
+			}
+			published = append(published, id)
+			batch++
+		}
+		err = rows.Err()
+		_ = rows.Close()
+		if err != nil {
+			return published, fmt.Errorf("failed to publish scheduled posts: %w", err)
+		}
+
+		if batch < publishBatchSize {
+			break
+		}
+	}
+
+	if len(published) > 0 {
+		log.Printf("[jobs] publisher: published %d scheduled post(s)", len(published))
+		if p.OnPublish != nil {
+			p.OnPublish(published)
+		}
+	}
+
+	return published, nil
+}
@@ -0,0 +1,45 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/nicolasbonnici/gorest-blog/trash"
+)
+
+const DefaultPurgeInterval = time.Hour
+
+// Purger deletes for good the posts and comments that stayed in the trash longer than
+// the retention period.
+type Purger struct {
+	trash     *trash.Store
+	retention time.Duration
+	interval  time.Duration
+}
+
+func NewPurger(store *trash.Store, retention time.Duration) *Purger {
+	return &Purger{
+		trash:     store,
+		retention: retention,
+		interval:  DefaultPurgeInterval,
+	}
+}
+
+// Run purges expired trash every interval until ctx is cancelled.
+func (p *Purger) Run(ctx context.Context) {
+	runEvery(ctx, "trash purger", p.interval, func(ctx context.Context) error {
+		_, err := p.PurgeExpired(ctx)
+		return err
+	})
+}
+
+// PurgeExpired deletes the rows trashed more than the retention period ago and returns
+// how many were removed.
+func (p *Purger) PurgeExpired(ctx context.Context) (int, error) {
+	purged, err := p.trash.PurgeOlderThan(ctx, time.Now().Add(-p.retention))
+	if purged > 0 {
+		log.Printf("[jobs] trash purger: purged %d trashed row(s)", purged)
+	}
+	return purged, err
+}
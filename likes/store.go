@@ -0,0 +1,253 @@
+package likes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nicolasbonnici/gorest-blog/types"
+	"github.com/nicolasbonnici/gorest-blog/visibility"
+	"github.com/nicolasbonnici/gorest/database"
+)
+
+// Likeable targets, matching the likes.likeable check constraint.
+const (
+	TargetPost    = "post"
+	TargetComment = "comment"
+)
+
+// DefaultKind is the reaction recorded by plain likes.
+const DefaultKind = "like"
+
+var (
+	ErrUnknownTarget = errors.New("likeable must be post or comment")
+	ErrUnknownKind   = errors.New("unknown reaction kind")
+)
+
+// targetTables maps a likeable target to the table holding it.
+var targetTables = map[string]string{
+	TargetPost:    "post",
+	TargetComment: "comment",
+}
+
+// State is the state of one reaction kind on a target for one user. Count is the number
+// of reactions of every kind on the target.
+type State struct {
+	Likeable   string `json:"likeable"`
+	LikeableId string `json:"likeableId"`
+	Kind       string `json:"kind"`
+	Liked      bool   `json:"liked"`
+	Count      int    `json:"likeCount"`
+}
+
+// Reactions aggregates the reactions on a target. Mine lists the kinds used by the viewer.
+type Reactions struct {
+	Likeable   string         `json:"likeable"`
+	LikeableId string         `json:"likeableId"`
+	Counts     map[string]int `json:"counts"`
+	Total      int            `json:"total"`
+	Mine       []string       `json:"mine"`
+}
+
+// Store writes likes on behalf of a liker. The liked user and the like date are always
+// derived server-side.
+type Store struct {
+	db    database.Database
+	kinds []string
+}
+
+// NewStore returns a store accepting the reaction kinds listed in kinds, the first one
+// being the default. DefaultKind is used when kinds is empty.
+func NewStore(db database.Database, kinds []string) *Store {
+	if len(kinds) == 0 {
+		kinds = []string{DefaultKind}
+	}
+	return &Store{
+		db:    db,
+		kinds: kinds,
+	}
+}
+
+func (s *Store) Kinds() []string {
+	return s.kinds
+}
+
+// DefaultKind returns the kind recorded when none is given.
+func (s *Store) DefaultKind() string {
+	return s.kinds[0]
+}
+
+func (s *Store) IsKind(kind string) bool {
+	for _, k := range s.kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Exists reports whether the target likeable/id exists and is visible in scope: a post
+// outside the trash that scope may read, or an approved comment of such a post.
+func (s *Store) Exists(ctx context.Context, scope visibility.Scope, likeable, id string) (bool, error) {
+	table, ok := targetTables[likeable]
+	if !ok {
+		return false, ErrUnknownTarget
+	}
+
+	visible, args := visibleTarget(likeable, scope, []any{id})
+	var exists bool
+	query := fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM %s t WHERE t.id::text = $1 AND %s)", table, visible)
+	if err := s.db.QueryRow(ctx, query, args...).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check like target: %w", err)
+	}
+	return exists, nil
+}
+
+// Add records a reaction of kind on the target for likerID and returns the id of the new
+// like. inserted is false when likerID already reacted with kind or the target is not
+// visible in scope.
+func (s *Store) Add(ctx context.Context, scope visibility.Scope, likerID, likeable, id, kind string) (likeID string, inserted bool, err error) {
+	table, ok := targetTables[likeable]
+	if !ok {
+		return "", false, ErrUnknownTarget
+	}
+	if !s.IsKind(kind) {
+		return "", false, ErrUnknownKind
+	}
+
+	visible, args := visibleTarget(likeable, scope, []any{likerID, likeable, id, kind})
+	query := fmt.Sprintf(`
+		INSERT INTO likes (liker_id, liked_id, likeable, likeable_id, kind, liked_at)
+		SELECT $1::uuid, t.user_id, $2, t.id, $4, CURRENT_TIMESTAMP
+		FROM %s t
+		WHERE t.id::text = $3 AND %s
+		ON CONFLICT (liker_id, likeable, likeable_id, kind) DO NOTHING
+		RETURNING id`, table, visible)
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to add like: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	if !rows.Next() {
+		return "", false, nil
+	}
+	if err := rows.Scan(&likeID); err != nil {
+		return "", false, fmt.Errorf("failed to scan like: %w", err)
+	}
+	return likeID, true, nil
+}
+
+// Remove deletes the reaction of kind made by likerID on the target, if any.
+func (s *Store) Remove(ctx context.Context, likerID, likeable, id, kind string) error {
+	if _, ok := targetTables[likeable]; !ok {
+		return ErrUnknownTarget
+	}
+
+	query := "DELETE FROM likes WHERE liker_id = $1 AND likeable = $2 AND likeable_id::text = $3 AND kind = $4"
+	if _, err := s.db.Exec(ctx, query, likerID, likeable, id, kind); err != nil {
+		return fmt.Errorf("failed to remove like: %w", err)
+	}
+	return nil
+}
+
+// State returns whether likerID reacted with kind on the target and the target's like count.
+func (s *Store) State(ctx context.Context, likerID, likeable, id, kind string) (*State, error) {
+	table, ok := targetTables[likeable]
+	if !ok {
+		return nil, ErrUnknownTarget
+	}
+
+	query := fmt.Sprintf(`
+		SELECT t.id, t.like_count,
+		       EXISTS(SELECT 1 FROM likes l WHERE l.liker_id = $1 AND l.likeable = $2 AND l.likeable_id = t.id AND l.kind = $4)
+		FROM %s t
+		WHERE t.id::text = $3`, table)
+
+	state := &State{Likeable: likeable, Kind: kind}
+	if err := s.db.QueryRow(ctx, query, likerID, likeable, id, kind).Scan(&state.LikeableId, &state.Count, &state.Liked); err != nil {
+		return nil, fmt.Errorf("failed to read like state: %w", err)
+	}
+	return state, nil
+}
+
+// Reactions counts the reactions on the target per kind. Every configured kind is listed,
+// with a zero count when unused or when the target is not visible in scope. viewerID may
+// be empty for anonymous viewers.
+func (s *Store) Reactions(ctx context.Context, scope visibility.Scope, viewerID, likeable, id string) (*Reactions, error) {
+	table, ok := targetTables[likeable]
+	if !ok {
+		return nil, ErrUnknownTarget
+	}
+
+	visible, args := visibleTarget(likeable, scope, []any{likeable, id, viewerID})
+	query := fmt.Sprintf(`
+		SELECT l.kind, COUNT(*), BOOL_OR(l.liker_id::text = $3)
+		FROM likes l
+		JOIN %s t ON t.id = l.likeable_id
+		WHERE l.likeable = $1 AND l.likeable_id::text = $2 AND %s
+		GROUP BY l.kind
+		ORDER BY l.kind`, table, visible)
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count reactions: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	reactions := &Reactions{
+		Likeable:   likeable,
+		LikeableId: id,
+		Counts:     make(map[string]int, len(s.kinds)),
+		Mine:       make([]string, 0),
+	}
+	for _, kind := range s.kinds {
+		reactions.Counts[kind] = 0
+	}
+
+	for rows.Next() {
+		var kind string
+		var count int
+		var mine bool
+		if err := rows.Scan(&kind, &count, &mine); err != nil {
+			return nil, fmt.Errorf("failed to scan reaction count: %w", err)
+		}
+		reactions.Counts[kind] = count
+		reactions.Total += count
+		if mine {
+			reactions.Mine = append(reactions.Mine, kind)
+		}
+	}
+
+	return reactions, nil
+}
+
+// visibleTarget returns the condition selecting the targets, aliased t, visible in scope,
+// and args extended with its arguments. Comments are visible when approved and attached to
+// a visible post.
+func visibleTarget(likeable string, scope visibility.Scope, args []any) (string, []any) {
+	alias := "t"
+	cond := ""
+	if likeable == TargetComment {
+		args = append(args, types.CommentStatusApproved.String())
+		cond = fmt.Sprintf("t.deleted_at IS NULL AND t.status = $%d AND ", len(args))
+		alias = "p"
+	}
+
+	post := alias + ".deleted_at IS NULL"
+	visible, visibleArgs := scope.Condition(alias)
+	for _, arg := range visibleArgs {
+		args = append(args, arg)
+		visible = strings.Replace(visible, "?", fmt.Sprintf("$%d", len(args)), 1)
+	}
+	if visible != "" {
+		post += " AND " + visible
+	}
+
+	if likeable == TargetComment {
+		return cond + "EXISTS (SELECT 1 FROM post p WHERE p.id = t.post_id AND " + post + ")", args
+	}
+	return post, args
+}
@@ -0,0 +1,278 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/nicolasbonnici/gorest-blog/models"
+	"github.com/nicolasbonnici/gorest/database"
+)
+
+const (
+	// DefaultMaxSize is the default size limit of an uploaded file, in bytes.
+	DefaultMaxSize = 10 << 20
+	// FilesPath is the route prefix under which stored files are served.
+	FilesPath = "/media/files/"
+
+	downloadTimeout = 30 * time.Second
+	// maxRedirects is the number of redirects a download may follow.
+	maxRedirects = 5
+)
+
+var (
+	ErrNotFound        = errors.New("media not found")
+	ErrTooLarge        = errors.New("file is too large")
+	ErrUnsupportedType = errors.New("unsupported file type: only JPEG, PNG, GIF and WebP images are accepted")
+	ErrForbiddenHost   = errors.New("media URL does not resolve to a public address")
+)
+
+// extensions lists the accepted content types, as detected from the file bytes, and the
+// extension given to their storage key. SVG is left out as it may embed scripts.
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// Store records uploaded media in the media table and their bytes in a Storage.
+type Store struct {
+	db      database.Database
+	storage Storage
+	maxSize int64
+	baseURL string
+	client  *http.Client
+}
+
+// NewStore returns a store accepting files of at most maxSize bytes (DefaultMaxSize when
+// zero or negative). baseURL is the public URL of the site, used to build file URLs.
+func NewStore(db database.Database, storage Storage, maxSize int64, baseURL string) *Store {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	return &Store{
+		db:      db,
+		storage: storage,
+		maxSize: maxSize,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  newDownloadClient(),
+	}
+}
+
+// newDownloadClient returns the client of Download. It only connects to public addresses,
+// whatever the host resolves to at dial time, follows at most maxRedirects redirects and
+// ignores proxy settings, which would hide the address actually reached.
+func newDownloadClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: downloadTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublic(ip) {
+				return ErrForbiddenHost
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   downloadTimeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("invalid redirect to %q", req.URL.String())
+			}
+			return nil
+		},
+	}
+}
+
+// isPublic reports whether ip may be reached by downloads: loopback, private, link-local,
+// multicast and unspecified addresses are refused.
+func isPublic(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsUnspecified() &&
+		!ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast()
+}
+
+// checkHost resolves host and fails with ErrForbiddenHost when any of its addresses is
+// not public. The dialer checks the address again, as the name may resolve differently
+// when connecting.
+func checkHost(ctx context.Context, host string) error {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if !isPublic(addr.IP) {
+			return ErrForbiddenHost
+		}
+	}
+	return nil
+}
+
+func (s *Store) MaxSize() int64 {
+	return s.maxSize
+}
+
+// URL returns the public URL of the file of m.
+func (s *Store) URL(m *models.Media) string {
+	return s.baseURL + FilesPath + m.StorageKey
+}
+
+// Upload stores the file read from r on behalf of userID. The content type is detected
+// from the bytes, whatever the file name says.
+func (s *Store) Upload(ctx context.Context, userID, filename string, r io.Reader) (*models.Media, error) {
+	data, err := io.ReadAll(io.LimitReader(r, s.maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	if int64(len(data)) > s.maxSize {
+		return nil, ErrTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	ext, ok := extensions[contentType]
+	if !ok {
+		return nil, ErrUnsupportedType
+	}
+
+	key, err := newKey(ext)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.storage.Put(ctx, key, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+
+	m := &models.Media{
+		StorageKey:  key,
+		Filename:    path.Base(filename),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+	}
+	if userID != "" {
+		m.UserId = &userID
+	}
+
+	query := `
+		INSERT INTO media (user_id, storage_key, filename, content_type, size)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+
+	if err := s.db.QueryRow(ctx, query, m.UserId, m.StorageKey, m.Filename, m.ContentType, m.Size).Scan(&m.Id, &m.CreatedAt); err != nil {
+		_ = s.storage.Delete(ctx, key)
+		return nil, fmt.Errorf("failed to record media: %w", err)
+	}
+
+	return m, nil
+}
+
+// Download fetches the image at rawURL and stores it like an upload from userID. URLs
+// pointing to loopback, private or link-local addresses fail with ErrForbiddenHost.
+func (s *Store) Download(ctx context.Context, userID, rawURL string) (*models.Media, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid media URL %q", rawURL)
+	}
+	if err := checkHost(ctx, u.Hostname()); err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", rawURL, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create media request: %w", err)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", rawURL, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: status %d", rawURL, resp.StatusCode)
+	}
+
+	return s.Upload(ctx, userID, u.Path, resp.Body)
+}
+
+func (s *Store) Get(ctx context.Context, id string) (*models.Media, error) {
+	return s.getBy(ctx, "id::text", id)
+}
+
+func (s *Store) GetByKey(ctx context.Context, key string) (*models.Media, error) {
+	return s.getBy(ctx, "storage_key", key)
+}
+
+// Open returns the stored bytes of m.
+func (s *Store) Open(ctx context.Context, m *models.Media) (io.ReadCloser, error) {
+	return s.storage.Open(ctx, m.StorageKey)
+}
+
+// Delete removes m from the media table, which unsets it as cover of its posts, then
+// removes its file.
+func (s *Store) Delete(ctx context.Context, m *models.Media) error {
+	rows, err := s.db.Query(ctx, "DELETE FROM media WHERE id = $1", m.Id)
+	if err != nil {
+		return fmt.Errorf("failed to delete media: %w", err)
+	}
+	if err := rows.Close(); err != nil {
+		return fmt.Errorf("failed to delete media: %w", err)
+	}
+
+	return s.storage.Delete(ctx, m.StorageKey)
+}
+
+func (s *Store) getBy(ctx context.Context, column, value string) (*models.Media, error) {
+	query := fmt.Sprintf(`
+		SELECT id, user_id, storage_key, filename, content_type, size, created_at
+		FROM media
+		WHERE %s = $1`, column)
+
+	rows, err := s.db.Query(ctx, query, value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query media: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	if !rows.Next() {
+		return nil, ErrNotFound
+	}
+
+	var m models.Media
+	if err := rows.Scan(&m.Id, &m.UserId, &m.StorageKey, &m.Filename, &m.ContentType, &m.Size, &m.CreatedAt); err != nil {
+		return nil, fmt.Errorf("failed to scan media: %w", err)
+	}
+	return &m, nil
+}
+
+// newKey returns a random storage key, grouped by month.
+func newKey(ext string) (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("failed to generate media key: %w", err)
+	}
+	return time.Now().UTC().Format("2006/01/") + hex.EncodeToString(b[:]) + ext, nil
+}
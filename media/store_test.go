@@ -0,0 +1,79 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsPublic(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"::ffff:127.0.0.1", false},
+	}
+
+	for _, tt := range tests {
+		if got := isPublic(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("isPublic(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestDownloadRefusesLoopback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the loopback server was reached")
+	}))
+	defer server.Close()
+
+	store := NewStore(nil, nil, 0, "")
+	if _, err := store.Download(context.Background(), "", server.URL+"/image.png"); !errors.Is(err, ErrForbiddenHost) {
+		t.Errorf("Download: got error %v, want ErrForbiddenHost", err)
+	}
+}
+
+func TestDownloadClientRefusesLoopbackAtDial(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the loopback server was reached")
+	}))
+	defer server.Close()
+
+	// A name resolving to a public address when checked may resolve to another one when
+	// dialing: the dialer checks the address it connects to.
+	resp, err := newDownloadClient().Get(server.URL)
+	if err == nil {
+		_ = resp.Body.Close()
+	}
+	if !errors.Is(err, ErrForbiddenHost) {
+		t.Errorf("Get: got error %v, want ErrForbiddenHost", err)
+	}
+}
+
+func TestDownloadClientCapsRedirects(t *testing.T) {
+	client := newDownloadClient()
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+	via := make([]*http.Request, maxRedirects)
+
+	if err := client.CheckRedirect(req, via[:maxRedirects-1]); err != nil {
+		t.Errorf("CheckRedirect after %d redirects: %v", maxRedirects-1, err)
+	}
+	if err := client.CheckRedirect(req, via); err == nil {
+		t.Errorf("CheckRedirect after %d redirects: expected an error", maxRedirects)
+	}
+}
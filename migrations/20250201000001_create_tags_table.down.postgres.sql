@@ -0,0 +1,5 @@
+-- Rollback tags tables
+DROP INDEX IF EXISTS idx_post_tag_tag;
+DROP TABLE IF EXISTS post_tag CASCADE;
+DROP INDEX IF EXISTS uniq_tag_slug;
+DROP TABLE IF EXISTS tag CASCADE;
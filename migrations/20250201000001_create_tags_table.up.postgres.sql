@@ -0,0 +1,19 @@
+-- Create tags table and post/tag join table
+CREATE TABLE tag (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL,
+    slug TEXT NOT NULL,
+    updated_at TIMESTAMP(0) WITH TIME ZONE,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX uniq_tag_slug ON tag (slug);
+
+CREATE TABLE post_tag (
+    post_id UUID NOT NULL REFERENCES post(id) ON DELETE CASCADE,
+    tag_id UUID NOT NULL REFERENCES tag(id) ON DELETE CASCADE,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (post_id, tag_id)
+);
+
+CREATE INDEX idx_post_tag_tag ON post_tag (tag_id);
@@ -0,0 +1,5 @@
+-- Rollback categories tables
+DROP INDEX IF EXISTS idx_post_category_category;
+DROP TABLE IF EXISTS post_category CASCADE;
+DROP INDEX IF EXISTS uniq_category_slug;
+DROP TABLE IF EXISTS category CASCADE;
@@ -0,0 +1,20 @@
+-- Create categories table and post/category join table
+CREATE TABLE category (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL,
+    slug TEXT NOT NULL,
+    description TEXT,
+    updated_at TIMESTAMP(0) WITH TIME ZONE,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX uniq_category_slug ON category (slug);
+
+CREATE TABLE post_category (
+    post_id UUID NOT NULL REFERENCES post(id) ON DELETE CASCADE,
+    category_id UUID NOT NULL REFERENCES category(id) ON DELETE CASCADE,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (post_id, category_id)
+);
+
+CREATE INDEX idx_post_category_category ON post_category (category_id);
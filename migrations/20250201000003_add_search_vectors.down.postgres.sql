@@ -0,0 +1,12 @@
+-- Rollback full-text search
+DROP TRIGGER IF EXISTS update_comment_search ON comment;
+DROP TRIGGER IF EXISTS update_post_search ON post;
+DROP FUNCTION IF EXISTS comment_search_trigger();
+DROP FUNCTION IF EXISTS post_search_trigger();
+DROP INDEX IF EXISTS idx_comment_search;
+DROP INDEX IF EXISTS idx_post_search;
+ALTER TABLE comment DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE post DROP COLUMN IF EXISTS search_vector;
+DROP FUNCTION IF EXISTS blog_search_document(TEXT, TEXT);
+DROP FUNCTION IF EXISTS blog_search_language();
+DROP TABLE IF EXISTS blog_setting CASCADE;
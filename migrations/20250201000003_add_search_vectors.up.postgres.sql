@@ -0,0 +1,55 @@
+-- Full-text search over posts and comments
+--
+-- The text search configuration is stored in blog_setting. Searches only read it:
+-- the reindex command (search/cli, through search.SetLanguage) changes it and
+-- rebuilds the search vectors.
+CREATE TABLE blog_setting (
+    key TEXT PRIMARY KEY,
+    value TEXT NOT NULL,
+    updated_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO blog_setting (key, value) VALUES ('search_language', 'english');
+
+CREATE FUNCTION blog_search_language() RETURNS regconfig AS $$
+    SELECT COALESCE(
+        (SELECT value FROM blog_setting WHERE key = 'search_language'),
+        'english'
+    )::regconfig
+$$ LANGUAGE sql STABLE;
+
+CREATE FUNCTION blog_search_document(title TEXT, body TEXT) RETURNS tsvector AS $$
+    SELECT setweight(to_tsvector(blog_search_language(), coalesce(title, '')), 'A') ||
+           setweight(to_tsvector(blog_search_language(), coalesce(body, '')), 'B')
+$$ LANGUAGE sql STABLE;
+
+ALTER TABLE post ADD COLUMN search_vector tsvector;
+ALTER TABLE comment ADD COLUMN search_vector tsvector;
+
+UPDATE post SET search_vector = blog_search_document(title, content);
+UPDATE comment SET search_vector = blog_search_document(NULL, content);
+
+CREATE INDEX idx_post_search ON post USING GIN (search_vector);
+CREATE INDEX idx_comment_search ON comment USING GIN (search_vector);
+
+CREATE FUNCTION post_search_trigger() RETURNS trigger AS $$
+BEGIN
+    NEW.search_vector := blog_search_document(NEW.title, NEW.content);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE FUNCTION comment_search_trigger() RETURNS trigger AS $$
+BEGIN
+    NEW.search_vector := blog_search_document(NULL, NEW.content);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER update_post_search
+    BEFORE INSERT OR UPDATE OF title, content ON post
+    FOR EACH ROW EXECUTE FUNCTION post_search_trigger();
+
+CREATE TRIGGER update_comment_search
+    BEFORE INSERT OR UPDATE OF content ON comment
+    FOR EACH ROW EXECUTE FUNCTION comment_search_trigger();
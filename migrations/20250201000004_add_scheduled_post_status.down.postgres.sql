@@ -0,0 +1,11 @@
+-- Rollback scheduled status: scheduled posts go back to drafts
+DROP INDEX IF EXISTS idx_post_status_published_at;
+
+UPDATE post SET status = 'drafted' WHERE status = 'scheduled';
+
+ALTER TYPE post_status RENAME TO post_status_old;
+CREATE TYPE post_status AS ENUM ('drafted', 'published');
+ALTER TABLE post ALTER COLUMN status DROP DEFAULT;
+ALTER TABLE post ALTER COLUMN status TYPE post_status USING status::text::post_status;
+ALTER TABLE post ALTER COLUMN status SET DEFAULT 'drafted';
+DROP TYPE post_status_old;
@@ -0,0 +1,4 @@
+-- Add the scheduled post status, published by the background publisher once published_at is due
+ALTER TYPE post_status ADD VALUE IF NOT EXISTS 'scheduled' BEFORE 'published';
+
+CREATE INDEX idx_post_status_published_at ON post (status, published_at);
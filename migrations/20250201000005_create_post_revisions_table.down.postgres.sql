@@ -0,0 +1,4 @@
+-- Rollback post revisions table
+DROP INDEX IF EXISTS idx_post_revision_user;
+DROP INDEX IF EXISTS uniq_post_revision_number;
+DROP TABLE IF EXISTS post_revision CASCADE;
@@ -0,0 +1,13 @@
+-- Create post revisions table, populated on every post update
+CREATE TABLE post_revision (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    post_id UUID NOT NULL REFERENCES post(id) ON DELETE CASCADE,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    number INTEGER NOT NULL,
+    title TEXT NOT NULL,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX uniq_post_revision_number ON post_revision (post_id, number);
+CREATE INDEX idx_post_revision_user ON post_revision (user_id);
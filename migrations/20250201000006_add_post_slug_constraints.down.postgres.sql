@@ -0,0 +1,7 @@
+-- Rollback slug constraints and history
+DROP INDEX IF EXISTS idx_post_slug_history_post;
+DROP INDEX IF EXISTS idx_post_slug_history_slug;
+DROP TABLE IF EXISTS post_slug_history CASCADE;
+DROP INDEX IF EXISTS uniq_post_slug;
+DROP INDEX IF EXISTS uniq_post_user_slug;
+ALTER TABLE post DROP COLUMN IF EXISTS slug_scope;
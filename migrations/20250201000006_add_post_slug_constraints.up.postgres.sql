@@ -0,0 +1,31 @@
+-- Enforce unique post slugs and keep the history of renamed slugs
+--
+-- Existing duplicates are suffixed with the start of the post id before the
+-- unique indexes are created. slug_scope records the scope a post's slug was
+-- allocated in: uniq_post_slug enforces the global scope on the posts allocated
+-- in it, and uniq_post_user_slug holds in both scopes, so changing slug_scope
+-- needs no schema change.
+UPDATE post p
+SET slug = p.slug || '-' || LEFT(p.id::text, 8)
+FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY slug ORDER BY created_at, id) AS position
+    FROM post
+) duplicates
+WHERE p.id = duplicates.id AND duplicates.position > 1;
+
+ALTER TABLE post ADD COLUMN slug_scope TEXT NOT NULL DEFAULT 'global'
+    CHECK (slug_scope IN ('global', 'author'));
+
+CREATE UNIQUE INDEX uniq_post_user_slug ON post (user_id, slug);
+CREATE UNIQUE INDEX uniq_post_slug ON post (slug) WHERE slug_scope = 'global';
+
+CREATE TABLE post_slug_history (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    post_id UUID NOT NULL REFERENCES post(id) ON DELETE CASCADE,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    slug TEXT NOT NULL,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_post_slug_history_slug ON post_slug_history (slug);
+CREATE INDEX idx_post_slug_history_post ON post_slug_history (post_id);
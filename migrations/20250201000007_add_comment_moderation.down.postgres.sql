@@ -0,0 +1,8 @@
+-- Rollback comment moderation
+ALTER TABLE post DROP COLUMN IF EXISTS require_comment_approval;
+
+DROP INDEX IF EXISTS idx_comment_post_status;
+DROP INDEX IF EXISTS idx_comment_status_created_at;
+
+ALTER TABLE comment DROP COLUMN IF EXISTS status;
+DROP TYPE IF EXISTS comment_status;
@@ -0,0 +1,14 @@
+-- Add comment moderation statuses
+--
+-- Existing comments stay visible. New comments are inserted as approved or pending by the
+-- application, depending on the global setting and the per-post override below.
+CREATE TYPE comment_status AS ENUM ('pending', 'approved', 'rejected', 'spam');
+
+ALTER TABLE comment ADD COLUMN status comment_status NOT NULL DEFAULT 'approved';
+ALTER TABLE comment ALTER COLUMN status SET DEFAULT 'pending';
+
+CREATE INDEX idx_comment_status_created_at ON comment (status, created_at);
+CREATE INDEX idx_comment_post_status ON comment (post_id, status);
+
+-- NULL follows the plugin's require_comment_approval setting
+ALTER TABLE post ADD COLUMN require_comment_approval BOOLEAN;
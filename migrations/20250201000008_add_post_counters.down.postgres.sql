@@ -0,0 +1,17 @@
+-- Rollback denormalized counters
+DROP FUNCTION IF EXISTS blog_repair_counters();
+
+DROP TRIGGER IF EXISTS count_comments ON comment;
+DROP TRIGGER IF EXISTS count_likes ON likes;
+DROP FUNCTION IF EXISTS comment_count_trigger();
+DROP FUNCTION IF EXISTS likes_count_trigger();
+
+DROP TRIGGER IF EXISTS guard_comment_counters ON comment;
+DROP TRIGGER IF EXISTS guard_post_counters ON post;
+DROP FUNCTION IF EXISTS comment_counters_guard();
+DROP FUNCTION IF EXISTS post_counters_guard();
+DROP FUNCTION IF EXISTS blog_counting();
+
+ALTER TABLE comment DROP COLUMN IF EXISTS like_count;
+ALTER TABLE post DROP COLUMN IF EXISTS comment_count;
+ALTER TABLE post DROP COLUMN IF EXISTS like_count;
@@ -0,0 +1,145 @@
+-- Denormalized like and comment counters
+--
+-- Counters are maintained by triggers in the same transaction as the like or comment
+-- change. comment_count only counts approved comments. Writes coming from the API cannot
+-- change the counters: the guard triggers keep the stored values unless the change is made
+-- by the counting triggers or blog_repair_counters(), which set blog.counting for that.
+ALTER TABLE post ADD COLUMN like_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE post ADD COLUMN comment_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE comment ADD COLUMN like_count INTEGER NOT NULL DEFAULT 0;
+
+CREATE FUNCTION blog_counting() RETURNS boolean AS $$
+    SELECT COALESCE(current_setting('blog.counting', true), '') = 'on'
+$$ LANGUAGE sql STABLE;
+
+CREATE FUNCTION post_counters_guard() RETURNS trigger AS $$
+BEGIN
+    IF NOT blog_counting() THEN
+        IF TG_OP = 'INSERT' THEN
+            NEW.like_count := 0;
+            NEW.comment_count := 0;
+        ELSE
+            NEW.like_count := OLD.like_count;
+            NEW.comment_count := OLD.comment_count;
+        END IF;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE FUNCTION comment_counters_guard() RETURNS trigger AS $$
+BEGIN
+    IF NOT blog_counting() THEN
+        IF TG_OP = 'INSERT' THEN
+            NEW.like_count := 0;
+        ELSE
+            NEW.like_count := OLD.like_count;
+        END IF;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER guard_post_counters
+    BEFORE INSERT OR UPDATE ON post
+    FOR EACH ROW EXECUTE FUNCTION post_counters_guard();
+
+CREATE TRIGGER guard_comment_counters
+    BEFORE INSERT OR UPDATE ON comment
+    FOR EACH ROW EXECUTE FUNCTION comment_counters_guard();
+
+CREATE FUNCTION likes_count_trigger() RETURNS trigger AS $$
+DECLARE
+    previous TEXT := current_setting('blog.counting', true);
+BEGIN
+    PERFORM set_config('blog.counting', 'on', true);
+
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        IF OLD.likeable = 'post' THEN
+            UPDATE post SET like_count = like_count - 1 WHERE id = OLD.likeable_id;
+        ELSE
+            UPDATE comment SET like_count = like_count - 1 WHERE id = OLD.likeable_id;
+        END IF;
+    END IF;
+
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        IF NEW.likeable = 'post' THEN
+            UPDATE post SET like_count = like_count + 1 WHERE id = NEW.likeable_id;
+        ELSE
+            UPDATE comment SET like_count = like_count + 1 WHERE id = NEW.likeable_id;
+        END IF;
+    END IF;
+
+    PERFORM set_config('blog.counting', COALESCE(previous, ''), true);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE FUNCTION comment_count_trigger() RETURNS trigger AS $$
+DECLARE
+    previous TEXT := current_setting('blog.counting', true);
+BEGIN
+    PERFORM set_config('blog.counting', 'on', true);
+
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        IF OLD.status = 'approved' THEN
+            UPDATE post SET comment_count = comment_count - 1 WHERE id = OLD.post_id;
+        END IF;
+    END IF;
+
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        IF NEW.status = 'approved' THEN
+            UPDATE post SET comment_count = comment_count + 1 WHERE id = NEW.post_id;
+        END IF;
+    END IF;
+
+    PERFORM set_config('blog.counting', COALESCE(previous, ''), true);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER count_likes
+    AFTER INSERT OR DELETE OR UPDATE OF likeable, likeable_id ON likes
+    FOR EACH ROW EXECUTE FUNCTION likes_count_trigger();
+
+CREATE TRIGGER count_comments
+    AFTER INSERT OR DELETE OR UPDATE OF status, post_id ON comment
+    FOR EACH ROW EXECUTE FUNCTION comment_count_trigger();
+
+-- Recomputes every counter from the likes and comment tables and returns the number of
+-- posts and comments whose counters were wrong.
+CREATE FUNCTION blog_repair_counters() RETURNS INTEGER AS $$
+DECLARE
+    fixed_posts INTEGER;
+    fixed_comments INTEGER;
+BEGIN
+    PERFORM set_config('blog.counting', 'on', true);
+
+    UPDATE post p
+    SET like_count = actual.likes, comment_count = actual.comments
+    FROM (
+        SELECT p.id,
+               (SELECT COUNT(*) FROM likes l WHERE l.likeable = 'post' AND l.likeable_id = p.id) AS likes,
+               (SELECT COUNT(*) FROM comment c WHERE c.post_id = p.id AND c.status = 'approved') AS comments
+        FROM post p
+    ) actual
+    WHERE p.id = actual.id
+      AND (p.like_count <> actual.likes OR p.comment_count <> actual.comments);
+    GET DIAGNOSTICS fixed_posts = ROW_COUNT;
+
+    UPDATE comment c
+    SET like_count = actual.likes
+    FROM (
+        SELECT c.id,
+               (SELECT COUNT(*) FROM likes l WHERE l.likeable = 'comment' AND l.likeable_id = c.id) AS likes
+        FROM comment c
+    ) actual
+    WHERE c.id = actual.id AND c.like_count <> actual.likes;
+    GET DIAGNOSTICS fixed_comments = ROW_COUNT;
+
+    PERFORM set_config('blog.counting', '', true);
+    RETURN fixed_posts + fixed_comments;
+END;
+$$ LANGUAGE plpgsql;
+
+SELECT blog_repair_counters();
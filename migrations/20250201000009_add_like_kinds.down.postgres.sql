@@ -0,0 +1,13 @@
+-- Rollback reaction kinds, keeping one like per user and target
+DROP INDEX IF EXISTS idx_likes_likeable_kind;
+DROP INDEX IF EXISTS uniq_likes_liker_likeable_kind;
+
+DELETE FROM likes l
+USING likes other
+WHERE l.liker_id = other.liker_id
+  AND l.likeable = other.likeable
+  AND l.likeable_id = other.likeable_id
+  AND (l.created_at, l.id::text) > (other.created_at, other.id::text);
+
+CREATE UNIQUE INDEX uniq_likes_liker_likeable ON likes (liker_id, likeable, likeable_id);
+ALTER TABLE likes DROP COLUMN IF EXISTS kind;
@@ -0,0 +1,9 @@
+-- Add reaction kinds to likes
+--
+-- Existing likes become reactions of kind 'like'. A user may react once per kind on a
+-- target. like_count on posts and comments keeps counting every reaction.
+ALTER TABLE likes ADD COLUMN kind TEXT NOT NULL DEFAULT 'like';
+
+DROP INDEX IF EXISTS uniq_likes_liker_likeable;
+CREATE UNIQUE INDEX uniq_likes_liker_likeable_kind ON likes (liker_id, likeable, likeable_id, kind);
+CREATE INDEX idx_likes_likeable_kind ON likes (likeable, likeable_id, kind);
@@ -0,0 +1,5 @@
+-- Rollback post excerpts, word counts and reading times
+ALTER TABLE post DROP COLUMN IF EXISTS reading_time_minutes;
+ALTER TABLE post DROP COLUMN IF EXISTS word_count;
+ALTER TABLE post DROP COLUMN IF EXISTS excerpt_override;
+ALTER TABLE post DROP COLUMN IF EXISTS excerpt;
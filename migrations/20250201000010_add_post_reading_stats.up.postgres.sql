@@ -0,0 +1,25 @@
+-- Add excerpts, word counts and reading times to posts
+--
+-- The application computes them from the rendered content on every save; excerpt_override
+-- holds an excerpt written by the author, used instead of the computed one. Existing posts
+-- get an approximation from their raw markdown until they are saved again.
+ALTER TABLE post ADD COLUMN excerpt TEXT NOT NULL DEFAULT '';
+ALTER TABLE post ADD COLUMN excerpt_override TEXT;
+ALTER TABLE post ADD COLUMN word_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE post ADD COLUMN reading_time_minutes INTEGER NOT NULL DEFAULT 0;
+
+UPDATE post
+SET word_count = stats.words,
+    reading_time_minutes = CEIL(stats.words / 200.0)::integer,
+    excerpt = LEFT(stats.text, 200)
+FROM (
+    SELECT id,
+           text,
+           CASE WHEN text = '' THEN 0 ELSE array_length(regexp_split_to_array(text, '\s+'), 1) END AS words
+    FROM (
+        SELECT id,
+               btrim(regexp_replace(regexp_replace(content, '[#*_`>~\[\]]+', '', 'g'), '\s+', ' ', 'g')) AS text
+        FROM post
+    ) stripped
+) stats
+WHERE post.id = stats.id;
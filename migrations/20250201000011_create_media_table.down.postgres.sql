@@ -0,0 +1,4 @@
+-- Rollback media table and post cover images. Stored files are left in place.
+DROP INDEX IF EXISTS idx_post_fk_cover_image;
+ALTER TABLE post DROP COLUMN IF EXISTS cover_image_id;
+DROP TABLE IF EXISTS media;
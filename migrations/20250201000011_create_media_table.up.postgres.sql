@@ -0,0 +1,19 @@
+-- Create media table for uploaded files, and post cover images
+--
+-- Files live in the configured storage under storage_key; this table holds their metadata.
+-- Deleting a media removes it from the posts using it as cover.
+CREATE TABLE media (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    storage_key TEXT NOT NULL,
+    filename TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX uniq_media_storage_key ON media (storage_key);
+CREATE INDEX idx_media_fk_user ON media (user_id);
+
+ALTER TABLE post ADD COLUMN cover_image_id UUID REFERENCES media(id) ON DELETE SET NULL;
+CREATE INDEX idx_post_fk_cover_image ON post (cover_image_id);
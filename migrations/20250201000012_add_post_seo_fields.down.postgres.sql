@@ -0,0 +1,7 @@
+-- Rollback post SEO fields
+ALTER TABLE post DROP COLUMN IF EXISTS og_image_url;
+ALTER TABLE post DROP COLUMN IF EXISTS og_description;
+ALTER TABLE post DROP COLUMN IF EXISTS og_title;
+ALTER TABLE post DROP COLUMN IF EXISTS meta_description;
+ALTER TABLE post DROP COLUMN IF EXISTS meta_title;
+ALTER TABLE post DROP COLUMN IF EXISTS canonical_url;
@@ -0,0 +1,10 @@
+-- Add canonical URL, SEO and Open Graph fields to posts
+--
+-- Every field is optional: empty ones fall back to the post title, excerpt, URL and cover
+-- image when the head metadata is built.
+ALTER TABLE post ADD COLUMN canonical_url TEXT;
+ALTER TABLE post ADD COLUMN meta_title TEXT;
+ALTER TABLE post ADD COLUMN meta_description TEXT;
+ALTER TABLE post ADD COLUMN og_title TEXT;
+ALTER TABLE post ADD COLUMN og_description TEXT;
+ALTER TABLE post ADD COLUMN og_image_url TEXT;
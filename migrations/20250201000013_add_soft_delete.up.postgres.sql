@@ -0,0 +1,73 @@
+-- Soft delete posts and comments
+--
+-- Deleting a post or a comment through the API sets deleted_at instead of removing the
+-- row, so it can be restored from the trash. Rows are only removed when purged from the
+-- trash, either explicitly or once older than the retention period. Trashed comments no
+-- longer count in comment_count.
+ALTER TABLE post ADD COLUMN deleted_at TIMESTAMP(0) WITH TIME ZONE;
+ALTER TABLE comment ADD COLUMN deleted_at TIMESTAMP(0) WITH TIME ZONE;
+
+CREATE INDEX idx_post_deleted_at ON post (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_comment_deleted_at ON comment (deleted_at) WHERE deleted_at IS NOT NULL;
+
+CREATE OR REPLACE FUNCTION comment_count_trigger() RETURNS trigger AS $$
+DECLARE
+    previous TEXT := current_setting('blog.counting', true);
+BEGIN
+    PERFORM set_config('blog.counting', 'on', true);
+
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        IF OLD.status = 'approved' AND OLD.deleted_at IS NULL THEN
+            UPDATE post SET comment_count = comment_count - 1 WHERE id = OLD.post_id;
+        END IF;
+    END IF;
+
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        IF NEW.status = 'approved' AND NEW.deleted_at IS NULL THEN
+            UPDATE post SET comment_count = comment_count + 1 WHERE id = NEW.post_id;
+        END IF;
+    END IF;
+
+    PERFORM set_config('blog.counting', COALESCE(previous, ''), true);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS count_comments ON comment;
+CREATE TRIGGER count_comments
+    AFTER INSERT OR DELETE OR UPDATE OF status, post_id, deleted_at ON comment
+    FOR EACH ROW EXECUTE FUNCTION comment_count_trigger();
+
+CREATE OR REPLACE FUNCTION blog_repair_counters() RETURNS INTEGER AS $$
+DECLARE
+    fixed_posts INTEGER;
+    fixed_comments INTEGER;
+BEGIN
+    PERFORM set_config('blog.counting', 'on', true);
+
+    UPDATE post p
+    SET like_count = actual.likes, comment_count = actual.comments
+    FROM (
+        SELECT p.id,
+               (SELECT COUNT(*) FROM likes l WHERE l.likeable = 'post' AND l.likeable_id = p.id) AS likes,
+               (SELECT COUNT(*) FROM comment c WHERE c.post_id = p.id AND c.status = 'approved' AND c.deleted_at IS NULL) AS comments
+        FROM post p
+    ) actual
+    WHERE p.id = actual.id
+      AND (p.like_count <> actual.likes OR p.comment_count <> actual.comments);
+    GET DIAGNOSTICS fixed_posts = ROW_COUNT;
+
+    UPDATE comment c
+    SET like_count = actual.likes
+    FROM (
+        SELECT c.id,
+               (SELECT COUNT(*) FROM likes l WHERE l.likeable = 'comment' AND l.likeable_id = c.id) AS likes
+        FROM comment c
+    ) actual
+    WHERE c.id = actual.id AND c.like_count <> actual.likes;
+    GET DIAGNOSTICS fixed_comments = ROW_COUNT;
+
+    PERFORM set_config('blog.counting', '', true);
+    RETURN fixed_posts + fixed_comments;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,2 @@
+-- Rollback post import source table
+DROP TABLE IF EXISTS post_import_source;
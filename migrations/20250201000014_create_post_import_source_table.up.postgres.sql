@@ -0,0 +1,20 @@
+-- Create post import source table, linking imported posts to the article they come from
+--
+-- Imports identify an article by the importing user, its engine and its source id, so that
+-- renamed articles update their post instead of creating a duplicate, and two users importing
+-- the same article each get their own post. content_hash is the hash of the article as
+-- last imported, used to skip articles that did not change.
+CREATE TABLE post_import_source (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    post_id UUID NOT NULL REFERENCES post(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    engine TEXT NOT NULL,
+    source_id TEXT NOT NULL,
+    source_url TEXT,
+    content_hash TEXT NOT NULL,
+    imported_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX uniq_post_import_source ON post_import_source (user_id, engine, source_id);
+CREATE INDEX idx_post_import_source_fk_post ON post_import_source (post_id);
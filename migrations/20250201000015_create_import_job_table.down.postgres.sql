@@ -0,0 +1,2 @@
+-- Rollback import job table
+DROP TABLE IF EXISTS import_job;
@@ -0,0 +1,33 @@
+-- Create import job table, queueing the imports requested over HTTP
+--
+-- Jobs are claimed by the import workers with FOR UPDATE SKIP LOCKED, so several replicas
+-- can share the queue. Workers write the progress of running jobs to their row, and stop a
+-- job soon after its status is set to 'cancelled'.
+CREATE TABLE import_job (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    engine TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'queued'
+        CHECK (status IN ('queued', 'running', 'succeeded', 'failed', 'cancelled')),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    username TEXT,
+    article_url TEXT,
+    article_id TEXT,
+    update_existing BOOLEAN NOT NULL DEFAULT FALSE,
+    dry_run BOOLEAN NOT NULL DEFAULT FALSE,
+    download_media BOOLEAN NOT NULL DEFAULT FALSE,
+    total_count INTEGER NOT NULL DEFAULT 0,
+    processed_count INTEGER NOT NULL DEFAULT 0,
+    created_count INTEGER NOT NULL DEFAULT 0,
+    updated_count INTEGER NOT NULL DEFAULT 0,
+    skipped_count INTEGER NOT NULL DEFAULT 0,
+    failed_count INTEGER NOT NULL DEFAULT 0,
+    message TEXT NOT NULL DEFAULT '',
+    errors TEXT[] NOT NULL DEFAULT '{}',
+    started_at TIMESTAMP(0) WITH TIME ZONE,
+    finished_at TIMESTAMP(0) WITH TIME ZONE,
+    updated_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_import_job_status ON import_job (status, created_at);
+CREATE INDEX idx_import_job_fk_user ON import_job (user_id);
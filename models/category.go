@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+type Category struct {
+	Id          string     `json:"id,omitempty" db:"id"`
+	Name        string     `json:"name" db:"name"`
+	Slug        string     `json:"slug" db:"slug"`
+	Description *string    `json:"description,omitempty" db:"description"`
+	UpdatedAt   *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+	CreatedAt   *time.Time `json:"createdAt,omitempty" db:"created_at"`
+}
+
+func (Category) TableName() string {
+	return "category"
+}
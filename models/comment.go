@@ -8,8 +8,11 @@ type Comment struct {
 	PostId    *string    `json:"postId,omitempty" db:"post_id"`
 	ParentId  *string    `json:"parentId,omitempty" db:"parent_id"`
 	Content   string     `json:"content" db:"content"`
+	Status    string     `json:"status" db:"status"`
+	LikeCount int        `json:"likeCount" db:"like_count"`
 	UpdatedAt *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
 	CreatedAt *time.Time `json:"createdAt,omitempty" db:"created_at"`
+	DeletedAt *time.Time `json:"deletedAt,omitempty" db:"deleted_at"`
 }
 
 func (Comment) TableName() string {
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+type ImportJob struct {
+	Id             string     `json:"id,omitempty" db:"id"`
+	Engine         string     `json:"engine" db:"engine"`
+	Status         string     `json:"status" db:"status"`
+	UserId         string     `json:"userId" db:"user_id"`
+	Username       *string    `json:"username,omitempty" db:"username"`
+	ArticleUrl     *string    `json:"articleUrl,omitempty" db:"article_url"`
+	ArticleId      *string    `json:"articleId,omitempty" db:"article_id"`
+	UpdateExisting bool       `json:"updateExisting" db:"update_existing"`
+	DryRun         bool       `json:"dryRun" db:"dry_run"`
+	DownloadMedia  bool       `json:"downloadMedia" db:"download_media"`
+	TotalCount     int        `json:"totalCount" db:"total_count"`
+	ProcessedCount int        `json:"processedCount" db:"processed_count"`
+	CreatedCount   int        `json:"createdCount" db:"created_count"`
+	UpdatedCount   int        `json:"updatedCount" db:"updated_count"`
+	SkippedCount   int        `json:"skippedCount" db:"skipped_count"`
+	FailedCount    int        `json:"failedCount" db:"failed_count"`
+	Message        string     `json:"message" db:"message"`
+	Errors         []string   `json:"errors" db:"errors"`
+	StartedAt      *time.Time `json:"startedAt,omitempty" db:"started_at"`
+	FinishedAt     *time.Time `json:"finishedAt,omitempty" db:"finished_at"`
+	UpdatedAt      *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+	CreatedAt      *time.Time `json:"createdAt,omitempty" db:"created_at"`
+}
+
+func (ImportJob) TableName() string {
+	return "import_job"
+}
@@ -8,6 +8,7 @@ type Like struct {
 	LikedId    *string    `json:"likedId,omitempty" db:"liked_id"`
 	Likeable   string     `json:"likeable" db:"likeable"`
 	LikeableId string     `json:"likeableId" db:"likeable_id"`
+	Kind       string     `json:"kind" db:"kind"`
 	LikedAt    time.Time  `json:"likedAt" db:"liked_at"`
 	UpdatedAt  *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
 	CreatedAt  *time.Time `json:"createdAt,omitempty" db:"created_at"`
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+type Media struct {
+	Id          string     `json:"id,omitempty" db:"id"`
+	UserId      *string    `json:"userId,omitempty" db:"user_id"`
+	StorageKey  string     `json:"storageKey" db:"storage_key"`
+	Filename    string     `json:"filename" db:"filename"`
+	ContentType string     `json:"contentType" db:"content_type"`
+	Size        int64      `json:"size" db:"size"`
+	CreatedAt   *time.Time `json:"createdAt,omitempty" db:"created_at"`
+}
+
+func (Media) TableName() string {
+	return "media"
+}
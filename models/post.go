@@ -3,15 +3,31 @@ package models
 import "time"
 
 type Post struct {
-	Id          string     `json:"id,omitempty" db:"id"`
-	UserId      *string    `json:"userId,omitempty" db:"user_id"`
-	Slug        string     `json:"slug" db:"slug"`
-	Status      string     `json:"status" db:"status"`
-	Title       string     `json:"title" db:"title"`
-	Content     string     `json:"content" db:"content"`
-	PublishedAt *time.Time `json:"publishedAt,omitempty" db:"published_at"`
-	UpdatedAt   *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
-	CreatedAt   *time.Time `json:"createdAt,omitempty" db:"created_at"`
+	Id                     string     `json:"id,omitempty" db:"id"`
+	UserId                 *string    `json:"userId,omitempty" db:"user_id"`
+	Slug                   string     `json:"slug" db:"slug"`
+	SlugScope              string     `json:"-" db:"slug_scope"`
+	Status                 string     `json:"status" db:"status"`
+	Title                  string     `json:"title" db:"title"`
+	Content                string     `json:"content" db:"content"`
+	Excerpt                string     `json:"excerpt" db:"excerpt"`
+	ExcerptOverride        *string    `json:"excerptOverride,omitempty" db:"excerpt_override"`
+	WordCount              int        `json:"wordCount" db:"word_count"`
+	ReadingTimeMinutes     int        `json:"readingTimeMinutes" db:"reading_time_minutes"`
+	CoverImageId           *string    `json:"coverImageId,omitempty" db:"cover_image_id"`
+	CanonicalUrl           *string    `json:"canonicalUrl,omitempty" db:"canonical_url"`
+	MetaTitle              *string    `json:"metaTitle,omitempty" db:"meta_title"`
+	MetaDescription        *string    `json:"metaDescription,omitempty" db:"meta_description"`
+	OgTitle                *string    `json:"ogTitle,omitempty" db:"og_title"`
+	OgDescription          *string    `json:"ogDescription,omitempty" db:"og_description"`
+	OgImageUrl             *string    `json:"ogImageUrl,omitempty" db:"og_image_url"`
+	PublishedAt            *time.Time `json:"publishedAt,omitempty" db:"published_at"`
+	RequireCommentApproval *bool      `json:"requireCommentApproval,omitempty" db:"require_comment_approval"`
+	LikeCount              int        `json:"likeCount" db:"like_count"`
+	CommentCount           int        `json:"commentCount" db:"comment_count"`
+	UpdatedAt              *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+	CreatedAt              *time.Time `json:"createdAt,omitempty" db:"created_at"`
+	DeletedAt              *time.Time `json:"deletedAt,omitempty" db:"deleted_at"`
 }
 
 func (Post) TableName() string {
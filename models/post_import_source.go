@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+type PostImportSource struct {
+	Id          string     `json:"id,omitempty" db:"id"`
+	PostId      string     `json:"postId" db:"post_id"`
+	UserId      string     `json:"userId" db:"user_id"`
+	Engine      string     `json:"engine" db:"engine"`
+	SourceId    string     `json:"sourceId" db:"source_id"`
+	SourceUrl   *string    `json:"sourceUrl,omitempty" db:"source_url"`
+	ContentHash string     `json:"contentHash" db:"content_hash"`
+	ImportedAt  *time.Time `json:"importedAt,omitempty" db:"imported_at"`
+	CreatedAt   *time.Time `json:"createdAt,omitempty" db:"created_at"`
+}
+
+func (PostImportSource) TableName() string {
+	return "post_import_source"
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+type PostRevision struct {
+	Id        string     `json:"id,omitempty" db:"id"`
+	PostId    string     `json:"postId" db:"post_id"`
+	UserId    *string    `json:"userId,omitempty" db:"user_id"`
+	Number    int        `json:"number" db:"number"`
+	Title     string     `json:"title" db:"title"`
+	Content   string     `json:"content" db:"content"`
+	CreatedAt *time.Time `json:"createdAt,omitempty" db:"created_at"`
+}
+
+func (PostRevision) TableName() string {
+	return "post_revision"
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+type Tag struct {
+	Id        string     `json:"id,omitempty" db:"id"`
+	Name      string     `json:"name" db:"name"`
+	Slug      string     `json:"slug" db:"slug"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+	CreatedAt *time.Time `json:"createdAt,omitempty" db:"created_at"`
+}
+
+func (Tag) TableName() string {
+	return "tag"
+}
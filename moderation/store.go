@@ -0,0 +1,103 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nicolasbonnici/gorest-blog/models"
+	"github.com/nicolasbonnici/gorest-blog/types"
+	"github.com/nicolasbonnici/gorest/database"
+)
+
+// Store reads and changes the moderation status of comments.
+type Store struct {
+	db database.Database
+}
+
+func NewStore(db database.Database) *Store {
+	return &Store{db: db}
+}
+
+// moderated restricts statements to comments that are not in the trash, on posts that are
+// not either.
+const moderated = "deleted_at IS NULL AND EXISTS (SELECT 1 FROM post p WHERE p.id = post_id AND p.deleted_at IS NULL)"
+
+// Queue returns a page of the comments having status, oldest first, and their total count.
+// Trashed comments and comments on trashed posts are left out.
+func (s *Store) Queue(ctx context.Context, status types.CommentStatus, limit, offset int) ([]models.Comment, int, error) {
+	var total int
+	if err := s.db.QueryRow(ctx, "SELECT COUNT(*) FROM comment WHERE status::text = $1 AND "+moderated, status.String()).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count comments: %w", err)
+	}
+
+	query := `
+		SELECT id, user_id, post_id, parent_id, content, status, like_count, updated_at, created_at
+		FROM comment
+		WHERE status::text = $1 AND ` + moderated + `
+		ORDER BY created_at, id
+		LIMIT $2 OFFSET $3`
+
+	rows, err := s.db.Query(ctx, query, status.String(), limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list comments: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	comments := make([]models.Comment, 0)
+	for rows.Next() {
+		var comment models.Comment
+		if err := rows.Scan(
+			&comment.Id,
+			&comment.UserId,
+			&comment.PostId,
+			&comment.ParentId,
+			&comment.Content,
+			&comment.Status,
+			&comment.LikeCount,
+			&comment.UpdatedAt,
+			&comment.CreatedAt,
+		); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan comment: %w", err)
+		}
+		comments = append(comments, comment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list comments: %w", err)
+	}
+
+	return comments, total, nil
+}
+
+// SetStatus moves the comments ids to status and returns the ids that exist. Ids that are
+// not UUIDs, of trashed comments or of comments on trashed posts are ignored.
+func (s *Store) SetStatus(ctx context.Context, ids []string, status types.CommentStatus) ([]string, error) {
+	query := `
+		UPDATE comment
+		SET status = $1::comment_status, updated_at = CURRENT_TIMESTAMP
+		WHERE id IN (
+			SELECT requested::uuid
+			FROM unnest($2::text[]) AS requested
+			WHERE requested ~* '^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$'
+		) AND ` + moderated + `
+		RETURNING id`
+
+	rows, err := s.db.Query(ctx, query, status.String(), ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update comment status: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	updated := make([]string, 0, len(ids))
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan comment id: %w", err)
+		}
+		updated = append(updated, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to update comment status: %w", err)
+	}
+
+	return updated, nil
+}
@@ -0,0 +1,76 @@
+package permalink
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/nicolasbonnici/gorest-blog/models"
+)
+
+// DefaultTemplate is the path of a post page unless configured otherwise.
+const DefaultTemplate = "/posts/{slug}"
+
+// placeholders lists the variables a path template may use.
+var placeholders = []string{"{slug}", "{id}", "{year}", "{month}", "{day}"}
+
+// Builder builds the public URLs of post pages from a base URL and a path template such
+// as "/posts/{slug}" or "/{year}/{month}/{slug}".
+type Builder struct {
+	baseURL  string
+	template string
+}
+
+func New(baseURL, template string) *Builder {
+	if template == "" {
+		template = DefaultTemplate
+	}
+	return &Builder{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		template: template,
+	}
+}
+
+// Validate checks that template is a path identifying a single post, i.e. that it uses
+// {slug} or {id}, and only known placeholders.
+func Validate(template string) error {
+	if !strings.HasPrefix(template, "/") {
+		return fmt.Errorf("post path template %q must start with /", template)
+	}
+	if !strings.Contains(template, "{slug}") && !strings.Contains(template, "{id}") {
+		return fmt.Errorf("post path template %q must contain {slug} or {id}", template)
+	}
+
+	rest := template
+	for _, placeholder := range placeholders {
+		rest = strings.ReplaceAll(rest, placeholder, "")
+	}
+	if strings.ContainsAny(rest, "{}") {
+		return fmt.Errorf("post path template %q uses an unknown placeholder, expected one of %v", template, placeholders)
+	}
+	return nil
+}
+
+// URL returns the absolute URL of the page of post. Dates come from the publication date,
+// or the creation date for unpublished posts.
+func (b *Builder) URL(post models.Post) string {
+	date := time.Now()
+	switch {
+	case post.PublishedAt != nil:
+		date = *post.PublishedAt
+	case post.CreatedAt != nil:
+		date = *post.CreatedAt
+	}
+	date = date.UTC()
+
+	path := strings.NewReplacer(
+		"{slug}", url.PathEscape(post.Slug),
+		"{id}", url.PathEscape(post.Id),
+		"{year}", date.Format("2006"),
+		"{month}", date.Format("01"),
+		"{day}", date.Format("02"),
+	).Replace(b.template)
+
+	return b.baseURL + path
+}
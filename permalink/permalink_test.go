@@ -0,0 +1,73 @@
+package permalink
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nicolasbonnici/gorest-blog/models"
+)
+
+func TestValidate(t *testing.T) {
+	valid := []string{"/posts/{slug}", "/{year}/{month}/{day}/{slug}", "/p/{id}"}
+	for _, template := range valid {
+		if err := Validate(template); err != nil {
+			t.Errorf("Validate(%q): %v", template, err)
+		}
+	}
+
+	invalid := []string{"posts/{slug}", "/{year}/{month}", "/posts/{title}", "/posts/{slug"}
+	for _, template := range invalid {
+		if err := Validate(template); err == nil {
+			t.Errorf("Validate(%q): expected an error", template)
+		}
+	}
+}
+
+func TestURL(t *testing.T) {
+	published := time.Date(2024, 3, 5, 23, 30, 0, 0, time.FixedZone("UTC-2", -2*3600))
+	created := time.Date(2023, 12, 31, 8, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		baseURL  string
+		template string
+		post     models.Post
+		want     string
+	}{
+		{
+			name:    "default template",
+			baseURL: "https://blog.example.com/",
+			post:    models.Post{Slug: "hello-world"},
+			want:    "https://blog.example.com/posts/hello-world",
+		},
+		{
+			name:     "publication date in UTC",
+			baseURL:  "https://blog.example.com",
+			template: "/{year}/{month}/{day}/{slug}",
+			post:     models.Post{Slug: "hello", PublishedAt: &published, CreatedAt: &created},
+			want:     "https://blog.example.com/2024/03/06/hello",
+		},
+		{
+			name:     "creation date of unpublished posts",
+			baseURL:  "https://blog.example.com",
+			template: "/{year}/{slug}",
+			post:     models.Post{Slug: "draft", CreatedAt: &created},
+			want:     "https://blog.example.com/2023/draft",
+		},
+		{
+			name:     "escaped values",
+			baseURL:  "https://blog.example.com",
+			template: "/p/{id}/{slug}",
+			post:     models.Post{Id: "42", Slug: "a b/c"},
+			want:     "https://blog.example.com/p/42/a%20b%2Fc",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := New(tt.baseURL, tt.template).URL(tt.post); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,22 +1,39 @@
 package blog
 
 import (
+	"context"
 	"embed"
+	"fmt"
+	"log"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/nicolasbonnici/gorest-blog/importer"
+	"github.com/nicolasbonnici/gorest-blog/jobs"
+	"github.com/nicolasbonnici/gorest-blog/media"
+	"github.com/nicolasbonnici/gorest-blog/permalink"
+	"github.com/nicolasbonnici/gorest-blog/policy"
+	"github.com/nicolasbonnici/gorest-blog/resources"
+	"github.com/nicolasbonnici/gorest-blog/search"
+	"github.com/nicolasbonnici/gorest-blog/slug"
+	"github.com/nicolasbonnici/gorest-blog/trash"
 	"github.com/nicolasbonnici/gorest/database"
 	"github.com/nicolasbonnici/gorest/migrations"
 	"github.com/nicolasbonnici/gorest/plugin"
 
 	_ "github.com/nicolasbonnici/gorest-blog/importer/engines/devto"
+	_ "github.com/nicolasbonnici/gorest-blog/importer/engines/hashnode"
+	_ "github.com/nicolasbonnici/gorest-blog/importer/engines/medium"
 )
 
 //go:embed migrations/*.sql
 var migrationFiles embed.FS
 
 type BlogPlugin struct {
-	config Config
-	db     database.Database
+	config     Config
+	db         database.Database
+	stopWorker context.CancelFunc
+	imports    *importer.Queue
 }
 
 func NewPlugin() plugin.Plugin {
@@ -47,9 +64,150 @@ func (p *BlogPlugin) Initialize(config map[string]interface{}) error {
 		p.config.EnableImporter = enableImporter
 	}
 
+	if importWorkers, ok := config["import_workers"].(int); ok && importWorkers > 0 {
+		p.config.ImportWorkers = importWorkers
+	}
+
+	if importDownloadMedia, ok := config["import_download_media"].(bool); ok {
+		p.config.ImportDownloadMedia = importDownloadMedia
+	}
+
+	if adminRole, ok := config["admin_role"].(string); ok {
+		p.config.AdminRole = adminRole
+	}
+
+	if editorRole, ok := config["editor_role"].(string); ok {
+		p.config.EditorRole = editorRole
+	}
+
+	if roleResolver, ok := config["role_resolver"].(policy.RoleResolver); ok {
+		p.config.RoleResolver = roleResolver
+	}
+
+	if searchLanguage, ok := config["search_language"].(string); ok {
+		p.config.SearchLanguage = searchLanguage
+	}
+
+	if siteURL, ok := config["site_url"].(string); ok {
+		p.config.SiteURL = siteURL
+	}
+
+	if siteTitle, ok := config["site_title"].(string); ok {
+		p.config.SiteTitle = siteTitle
+	}
+
+	if siteDescription, ok := config["site_description"].(string); ok {
+		p.config.SiteDescription = siteDescription
+	}
+
+	if feedItemCount, ok := config["feed_item_count"].(int); ok && feedItemCount > 0 {
+		p.config.FeedItemCount = feedItemCount
+	}
+
+	if enablePublisher, ok := config["enable_publisher"].(bool); ok {
+		p.config.EnablePublisher = enablePublisher
+	}
+
+	if publishInterval, ok := config["publish_interval"].(string); ok {
+		interval, err := time.ParseDuration(publishInterval)
+		if err != nil {
+			return fmt.Errorf("invalid publish_interval: %w", err)
+		}
+		p.config.PublishInterval = interval
+	}
+
+	if trashRetention, ok := config["trash_retention"].(string); ok {
+		retention, err := time.ParseDuration(trashRetention)
+		if err != nil || retention < 0 {
+			return fmt.Errorf("invalid trash_retention %q: expected a duration such as \"720h\", or \"0\" to disable purging", trashRetention)
+		}
+		p.config.TrashRetention = retention
+	}
+
+	if slugScope, ok := config["slug_scope"].(string); ok {
+		scope := slug.Scope(slugScope)
+		if !scope.IsValid() {
+			return fmt.Errorf("invalid slug_scope %q: expected %q or %q", slugScope, slug.ScopeGlobal, slug.ScopeAuthor)
+		}
+		p.config.SlugScope = scope
+	}
+
+	if requireCommentApproval, ok := config["require_comment_approval"].(bool); ok {
+		p.config.RequireCommentApproval = requireCommentApproval
+	}
+
+	if renderCacheSize, ok := config["render_cache_size"].(int); ok {
+		p.config.RenderCacheSize = renderCacheSize
+	}
+
+	if postPathTemplate, ok := config["post_path_template"].(string); ok {
+		if err := permalink.Validate(postPathTemplate); err != nil {
+			return fmt.Errorf("invalid post_path_template: %w", err)
+		}
+		p.config.PostPathTemplate = postPathTemplate
+	}
+
+	if mediaStorage, ok := config["media_storage"].(media.Storage); ok {
+		p.config.MediaStorage = mediaStorage
+	}
+
+	if mediaDir, ok := config["media_dir"].(string); ok && mediaDir != "" {
+		p.config.MediaDir = mediaDir
+	}
+
+	if mediaMaxSize, ok := config["media_max_size"].(int); ok && mediaMaxSize > 0 {
+		p.config.MediaMaxSize = int64(mediaMaxSize)
+	}
+
+	if reactionKinds, ok := config["reaction_kinds"]; ok {
+		kinds, err := parseReactionKinds(reactionKinds)
+		if err != nil {
+			return err
+		}
+		p.config.ReactionKinds = kinds
+	}
+
 	return nil
 }
 
+// parseReactionKinds accepts a list of strings, as given in code or decoded from YAML.
+// Kinds must be non-empty slugs, e.g. "like" or "raised-hands".
+func parseReactionKinds(value any) ([]string, error) {
+	var raw []string
+	switch v := value.(type) {
+	case []string:
+		raw = v
+	case []interface{}:
+		for _, item := range v {
+			kind, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid reaction_kinds: %v is not a string", item)
+			}
+			raw = append(raw, kind)
+		}
+	default:
+		return nil, fmt.Errorf("invalid reaction_kinds: expected a list of strings")
+	}
+
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("invalid reaction_kinds: at least one kind is required")
+	}
+
+	seen := make(map[string]bool, len(raw))
+	kinds := make([]string, 0, len(raw))
+	for _, kind := range raw {
+		if kind == "" || slug.Make(kind) != kind {
+			return nil, fmt.Errorf("invalid reaction kind %q: use lowercase letters, digits and hyphens", kind)
+		}
+		if !seen[kind] {
+			seen[kind] = true
+			kinds = append(kinds, kind)
+		}
+	}
+
+	return kinds, nil
+}
+
 func (p *BlogPlugin) Handler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		return c.Next()
@@ -61,15 +219,80 @@ func (p *BlogPlugin) SetupEndpoints(app *fiber.App) error {
 		return nil
 	}
 
-	RegisterBlogRoutes(app, p.db, p.config.PaginationLimit, p.config.MaxPaginationLimit)
+	opts := newOptions(p.db, p.config)
+	registerBlogRoutes(app, p.db, opts)
+	p.checkSearchLanguage()
 
 	if p.config.EnableImporter {
-		RegisterImporterRoutes(app, p.db)
+		var downloader importer.MediaDownloader
+		if p.config.ImportDownloadMedia {
+			downloader = opts.Media
+		}
+		p.imports = importer.NewQueue(p.db, downloader, p.config.ImportWorkers)
+		p.imports.OnImport = func(ids []string) {
+			for _, id := range ids {
+				opts.Renderer.Invalidate(id)
+			}
+			opts.Sitemap.Invalidate()
+		}
+		RegisterImporterRoutes(app, p.imports, opts.Policy)
 	}
 
+	p.startWorkers(opts)
+
 	return nil
 }
 
+// checkSearchLanguage warns when the search vectors were not built with the configured
+// language. Rebuilding them rewrites every post and comment, so it is left to the
+// reindex command (search/cli) instead of being run by each replica.
+func (p *BlogPlugin) checkSearchLanguage() {
+	language, err := search.Language(context.Background(), p.db)
+	if err != nil {
+		log.Printf("[blog] %v", err)
+		return
+	}
+	if language != p.config.SearchLanguage {
+		log.Printf("[blog] search_language is %q but the search index uses %q: run the reindex command with -language %s",
+			p.config.SearchLanguage, language, p.config.SearchLanguage)
+	}
+}
+
+// startWorkers launches the background jobs. They run until Shutdown is called.
+func (p *BlogPlugin) startWorkers(opts resources.Options) {
+	if p.stopWorker != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.stopWorker = cancel
+
+	if p.config.EnablePublisher {
+		log.Printf("[blog] Starting scheduled post publisher (every %s)", p.config.PublishInterval)
+		publisher := jobs.NewPublisher(p.db, p.config.PublishInterval)
+		publisher.OnPublish = func(ids []string) { opts.Sitemap.Invalidate() }
+		go publisher.Run(ctx)
+	}
+
+	if p.config.TrashRetention > 0 {
+		log.Printf("[blog] Starting trash purger (retention %s)", p.config.TrashRetention)
+		go jobs.NewPurger(trash.NewStore(p.db), p.config.TrashRetention).Run(ctx)
+	}
+
+	if p.imports != nil {
+		log.Printf("[blog] Starting %d import worker(s)", p.config.ImportWorkers)
+		go p.imports.Run(ctx)
+	}
+}
+
+// Shutdown stops the background jobs started by SetupEndpoints.
+func (p *BlogPlugin) Shutdown() {
+	if p.stopWorker != nil {
+		p.stopWorker()
+		p.stopWorker = nil
+	}
+}
+
 func (p *BlogPlugin) MigrationSource() interface{} {
 	return migrations.NewEmbeddedSource("blog", migrationFiles, "migrations", p.db)
 }
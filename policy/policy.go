@@ -0,0 +1,112 @@
+package policy
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+	auth "github.com/nicolasbonnici/gorest-auth"
+	"github.com/nicolasbonnici/gorest-blog/visibility"
+)
+
+const (
+	DefaultAdminRole  = "admin"
+	DefaultEditorRole = "editor"
+)
+
+// RoleResolver returns the role of the user performing the current request.
+type RoleResolver func(c *fiber.Ctx) string
+
+// Policy decides whether the authenticated user may mutate an owned resource, and which
+// posts they may read.
+// Owners may always mutate their own rows; users holding AdminRole may mutate any row.
+// Users holding AdminRole or EditorRole may read every post, including other authors' drafts.
+type Policy struct {
+	AdminRole    string
+	EditorRole   string
+	RoleResolver RoleResolver
+}
+
+func New(adminRole, editorRole string, resolver RoleResolver) *Policy {
+	if adminRole == "" {
+		adminRole = DefaultAdminRole
+	}
+	if editorRole == "" {
+		editorRole = DefaultEditorRole
+	}
+	if resolver == nil {
+		resolver = LocalsRoleResolver
+	}
+	return &Policy{
+		AdminRole:    adminRole,
+		EditorRole:   editorRole,
+		RoleResolver: resolver,
+	}
+}
+
+// LocalsRoleResolver reads the role stored under the "role" key of the request locals.
+func LocalsRoleResolver(c *fiber.Ctx) string {
+	if role, ok := c.Locals("role").(string); ok {
+		return role
+	}
+	return ""
+}
+
+func (p *Policy) IsAdmin(c *fiber.Ctx) bool {
+	return p.AdminRole != "" && p.RoleResolver(c) == p.AdminRole
+}
+
+// IsEditor reports whether the caller holds EditorRole or AdminRole.
+func (p *Policy) IsEditor(c *fiber.Ctx) bool {
+	return p.IsAdmin(c) || (p.EditorRole != "" && p.RoleResolver(c) == p.EditorRole)
+}
+
+// Authorize returns nil when the authenticated user owns the resource or is an admin,
+// a 401 error when nobody is authenticated and a 403 error otherwise.
+func (p *Policy) Authorize(c *fiber.Ctx, ownerID *string) *fiber.Error {
+	user := auth.GetAuthenticatedUser(c)
+	if user == nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "Authentication required")
+	}
+
+	if p.IsAdmin(c) {
+		return nil
+	}
+
+	if ownerID == nil || *ownerID != user.UserID {
+		return fiber.NewError(fiber.StatusForbidden, "Forbidden")
+	}
+
+	return nil
+}
+
+// AuthorizeEditor returns nil when the authenticated user is an editor or an admin,
+// a 401 error when nobody is authenticated and a 403 error otherwise.
+func (p *Policy) AuthorizeEditor(c *fiber.Ctx) *fiber.Error {
+	if auth.GetAuthenticatedUser(c) == nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "Authentication required")
+	}
+	if !p.IsEditor(c) {
+		return fiber.NewError(fiber.StatusForbidden, "Forbidden")
+	}
+	return nil
+}
+
+// Scope returns the posts the caller may read: every post for editors and admins,
+// published posts and their own posts for other authenticated users, and published posts
+// only for anonymous readers.
+func (p *Policy) Scope(c *fiber.Ctx) visibility.Scope {
+	user := auth.GetAuthenticatedUser(c)
+	if user == nil {
+		return visibility.Scope{}
+	}
+	return visibility.Scope{
+		ViewerID: user.UserID,
+		AllPosts: p.IsEditor(c),
+	}
+}
+
+// Context returns the request context carrying the caller's visibility scope, so that
+// posts read by id through PostHooks follow the same rule as listings.
+func (p *Policy) Context(c *fiber.Ctx) context.Context {
+	return visibility.WithScope(auth.Context(c), p.Scope(c))
+}
@@ -0,0 +1,194 @@
+package render
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"html"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	goldmarkhtml "github.com/yuin/goldmark/renderer/html"
+)
+
+const (
+	// DefaultCacheSize is the number of rendered documents kept in memory.
+	DefaultCacheSize = 512
+	// ExcerptLength is the maximum length of an excerpt, in characters.
+	ExcerptLength = 200
+	// WordsPerMinute is the reading speed used to estimate reading times.
+	WordsPerMinute = 200
+)
+
+var (
+	// plain renders documents that are not cached, for Analyze.
+	plain = New(0)
+
+	whitespace = regexp.MustCompile(`\s+`)
+	// blockEnds matches the tags after which text must be separated when stripping HTML.
+	blockEnds = regexp.MustCompile(`(?i)</(?:p|li|h[1-6]|pre|blockquote|td|th|tr|div)>|<br\s*/?>`)
+)
+
+// Rendered is a markdown document rendered as sanitized HTML, plain text and excerpt.
+type Rendered struct {
+	HTML    string
+	Text    string
+	Excerpt string
+}
+
+// Renderer turns post markdown into HTML that is safe to embed in a page.
+//
+// Markdown is rendered with raw HTML enabled, then the whole output goes through an
+// allow-list sanitizer, so inline HTML written by authors keeps working without opening
+// the door to scripts, event handlers or javascript: links.
+type Renderer struct {
+	markdown  goldmark.Markdown
+	sanitizer *bluemonday.Policy
+	stripper  *bluemonday.Policy
+
+	mu       sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List
+}
+
+type cacheEntry struct {
+	key      string
+	digest   [sha256.Size]byte
+	rendered Rendered
+}
+
+// New returns a renderer caching up to cacheSize documents. Caching is disabled when
+// cacheSize is zero or negative.
+func New(cacheSize int) *Renderer {
+	sanitizer := bluemonday.UGCPolicy()
+	sanitizer.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#-]+$`)).OnElements("code")
+
+	return &Renderer{
+		markdown: goldmark.New(
+			goldmark.WithExtensions(extension.GFM),
+			goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+			goldmark.WithRendererOptions(goldmarkhtml.WithUnsafe()),
+		),
+		sanitizer: sanitizer,
+		stripper:  bluemonday.StrictPolicy(),
+		capacity:  cacheSize,
+		entries:   make(map[string]*list.Element),
+		order:     list.New(),
+	}
+}
+
+// Render renders markdown. When key is not empty (typically a post id) the result is
+// cached under key until the markdown changes or Invalidate is called.
+func (r *Renderer) Render(key, markdown string) Rendered {
+	if key == "" || r.capacity <= 0 {
+		return r.render(markdown)
+	}
+
+	digest := sha256.Sum256([]byte(markdown))
+
+	r.mu.Lock()
+	if elem, ok := r.entries[key]; ok {
+		entry := elem.Value.(*cacheEntry)
+		if entry.digest == digest {
+			r.order.MoveToFront(elem)
+			r.mu.Unlock()
+			return entry.rendered
+		}
+	}
+	r.mu.Unlock()
+
+	rendered := r.render(markdown)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if elem, ok := r.entries[key]; ok {
+		r.order.Remove(elem)
+	}
+	r.entries[key] = r.order.PushFront(&cacheEntry{key: key, digest: digest, rendered: rendered})
+	for r.order.Len() > r.capacity {
+		oldest := r.order.Back()
+		r.order.Remove(oldest)
+		delete(r.entries, oldest.Value.(*cacheEntry).key)
+	}
+
+	return rendered
+}
+
+// Invalidate drops the cached rendering of key.
+func (r *Renderer) Invalidate(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if elem, ok := r.entries[key]; ok {
+		r.order.Remove(elem)
+		delete(r.entries, key)
+	}
+}
+
+func (r *Renderer) render(markdown string) Rendered {
+	var buf bytes.Buffer
+	if err := r.markdown.Convert([]byte(markdown), &buf); err != nil {
+		// goldmark only fails on writer errors, which a bytes.Buffer never returns.
+		buf.Reset()
+		buf.WriteString(html.EscapeString(markdown))
+	}
+
+	safeHTML := r.sanitizer.Sanitize(buf.String())
+	text := r.stripper.Sanitize(blockEnds.ReplaceAllString(safeHTML, "$0 "))
+	text = strings.TrimSpace(whitespace.ReplaceAllString(html.UnescapeString(text), " "))
+
+	return Rendered{
+		HTML:    safeHTML,
+		Text:    text,
+		Excerpt: Excerpt(text, ExcerptLength),
+	}
+}
+
+// Stats describes the length of a markdown document.
+type Stats struct {
+	Excerpt            string
+	WordCount          int
+	ReadingTimeMinutes int
+}
+
+// Analyze returns the excerpt, word count and estimated reading time of markdown, counted
+// on its rendered text so that markup and URLs are not taken as words.
+func Analyze(markdown string) Stats {
+	text := plain.Render("", markdown).Text
+	words := len(strings.Fields(text))
+	return Stats{
+		Excerpt:            Excerpt(text, ExcerptLength),
+		WordCount:          words,
+		ReadingTimeMinutes: ReadingTime(words),
+	}
+}
+
+// ReadingTime estimates the minutes needed to read words words, rounding up. Any non
+// empty document takes at least a minute.
+func ReadingTime(words int) int {
+	return (words + WordsPerMinute - 1) / WordsPerMinute
+}
+
+// Excerpt shortens text to at most maxLength characters, cutting at a word boundary and
+// appending an ellipsis when text is longer.
+func Excerpt(text string, maxLength int) string {
+	if utf8.RuneCountInString(text) <= maxLength {
+		return text
+	}
+
+	runes := []rune(text)
+	cut := string(runes[:maxLength])
+	if i := strings.LastIndexAny(cut, " \t\n"); i > 0 {
+		cut = cut[:i]
+	}
+
+	return strings.TrimRight(cut, " \t\n.,;:!?-") + "…"
+}
@@ -0,0 +1,136 @@
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderSanitizes(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "markdown",
+			markdown: "# Title\n\nSome **bold** text.",
+			contains: []string{`<h1 id="title">Title</h1>`, "<strong>bold</strong>"},
+		},
+		{
+			name:     "script tag",
+			markdown: "Hello <script>alert(1)</script> world",
+			contains: []string{"Hello"},
+			excludes: []string{"<script", "alert(1)"},
+		},
+		{
+			name:     "event handler",
+			markdown: `<img src="https://example.com/a.png" onerror="alert(1)">`,
+			contains: []string{`src="https://example.com/a.png"`},
+			excludes: []string{"onerror"},
+		},
+		{
+			name:     "javascript link",
+			markdown: "[click](javascript:alert(1)) and <a href=\"javascript:alert(2)\">here</a>",
+			excludes: []string{"javascript:"},
+		},
+		{
+			name:     "style",
+			markdown: "<style>body{display:none}</style><p style=\"color:red\">red</p>",
+			contains: []string{"red</p>"},
+			excludes: []string{"<style", "display:none", "style="},
+		},
+		{
+			name:     "code language class",
+			markdown: "```go\nfmt.Println(\"hi\")\n```",
+			contains: []string{`<code class="language-go">`},
+		},
+		{
+			name:     "other classes",
+			markdown: `<code class="evil">x</code>`,
+			excludes: []string{"evil"},
+		},
+	}
+
+	r := New(0)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			html := r.Render("", tt.markdown).HTML
+			for _, want := range tt.contains {
+				if !strings.Contains(html, want) {
+					t.Errorf("HTML %q does not contain %q", html, want)
+				}
+			}
+			for _, unwanted := range tt.excludes {
+				if strings.Contains(html, unwanted) {
+					t.Errorf("HTML %q contains %q", html, unwanted)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderText(t *testing.T) {
+	rendered := New(0).Render("", "# Title\n\nFirst &amp; *second*.\n\n- one\n- two")
+	if want := "Title First & second. one two"; rendered.Text != want {
+		t.Errorf("got text %q, want %q", rendered.Text, want)
+	}
+	if rendered.Excerpt != rendered.Text {
+		t.Errorf("got excerpt %q, want the whole short text", rendered.Excerpt)
+	}
+}
+
+func TestRenderCache(t *testing.T) {
+	r := New(1)
+
+	first := r.Render("post-1", "one")
+	if got := r.Render("post-1", "two"); got.Text != "two" || got == first {
+		t.Errorf("changed markdown served from cache: %+v", got)
+	}
+
+	r.Render("post-2", "three")
+	if _, ok := r.entries["post-1"]; ok {
+		t.Error("least recently used entry not evicted")
+	}
+
+	r.Invalidate("post-2")
+	if _, ok := r.entries["post-2"]; ok {
+		t.Error("invalidated entry still cached")
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	tests := []struct {
+		text string
+		max  int
+		want string
+	}{
+		{"short text", 20, "short text"},
+		{"cut at a word boundary", 12, "cut at a…"},
+		{"trailing punctuation, removed", 22, "trailing punctuation…"},
+		{"élégant café crème", 13, "élégant café…"},
+	}
+
+	for _, tt := range tests {
+		if got := Excerpt(tt.text, tt.max); got != tt.want {
+			t.Errorf("Excerpt(%q, %d) = %q, want %q", tt.text, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	stats := Analyze("A [link](https://example.com/a/very/long/url) and `code`.")
+	if stats.WordCount != 4 {
+		t.Errorf("got %d words, want 4", stats.WordCount)
+	}
+	if stats.ReadingTimeMinutes != 1 {
+		t.Errorf("got %d minutes, want 1", stats.ReadingTimeMinutes)
+	}
+
+	if got := ReadingTime(0); got != 0 {
+		t.Errorf("ReadingTime(0) = %d, want 0", got)
+	}
+	if got := ReadingTime(WordsPerMinute + 1); got != 2 {
+		t.Errorf("ReadingTime(%d) = %d, want 2", WordsPerMinute+1, got)
+	}
+}
@@ -0,0 +1,170 @@
+package resources
+
+import (
+	"net/url"
+
+	"github.com/gofiber/fiber/v2"
+	auth "github.com/nicolasbonnici/gorest-auth"
+	"github.com/nicolasbonnici/gorest-blog/models"
+	"github.com/nicolasbonnici/gorest-blog/policy"
+	"github.com/nicolasbonnici/gorest-blog/slug"
+	"github.com/nicolasbonnici/gorest/crud"
+	"github.com/nicolasbonnici/gorest/database"
+	"github.com/nicolasbonnici/gorest/filter"
+	"github.com/nicolasbonnici/gorest/pagination"
+	"github.com/nicolasbonnici/gorest/response"
+)
+
+type CategoryResource struct {
+	DB                 database.Database
+	CRUD               *crud.CRUD[models.Category]
+	PaginationLimit    int
+	PaginationMaxLimit int
+	Policy             *policy.Policy
+}
+
+func RegisterCategoryRoutes(app *fiber.App, db database.Database, opts Options) {
+	res := &CategoryResource{
+		DB:                 db,
+		CRUD:               crud.New[models.Category](db),
+		PaginationLimit:    opts.PaginationLimit,
+		PaginationMaxLimit: opts.PaginationMaxLimit,
+		Policy:             opts.Policy,
+	}
+
+	app.Get("/categories", res.List)
+	app.Get("/categories/:id", res.Get)
+	app.Post("/categories", res.Create)
+	app.Put("/categories/:id", res.Update)
+	app.Delete("/categories/:id", res.Delete)
+}
+
+func (r *CategoryResource) List(c *fiber.Ctx) error {
+	limit := pagination.ParseIntQuery(c, "limit", r.PaginationLimit, r.PaginationMaxLimit)
+	page := pagination.ParseIntQuery(c, "page", 1, 10000)
+	if page < 1 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+	includeCount := c.Query("count", "true") != "false"
+
+	allowedFields := []string{"id", "name", "slug", "description", "updated_at", "created_at"}
+
+	queryParams := make(url.Values)
+	c.Context().QueryArgs().VisitAll(func(key, value []byte) {
+		queryParams.Add(string(key), string(value))
+	})
+
+	filters := filter.NewFilterSet(allowedFields, r.DB.Dialect())
+	if err := filters.ParseFromQuery(queryParams); err != nil {
+		return pagination.SendPaginatedError(c, 400, err.Error())
+	}
+	whereClause, whereArgs := filters.BuildWhereClause()
+
+	ordering := filter.NewOrderSet(allowedFields)
+	if err := ordering.ParseFromQuery(queryParams); err != nil {
+		return pagination.SendPaginatedError(c, 400, err.Error())
+	}
+	orderByClause := ordering.BuildOrderByClause()
+
+	result, err := r.CRUD.GetAllPaginated(auth.Context(c), crud.PaginationOptions{
+		Limit:         limit,
+		Offset:        offset,
+		IncludeCount:  includeCount,
+		WhereClause:   whereClause,
+		WhereArgs:     whereArgs,
+		OrderByClause: orderByClause,
+	})
+	if err != nil {
+		return pagination.SendPaginatedError(c, 500, err.Error())
+	}
+
+	return pagination.SendHydraCollection(c, result.Items, result.Total, limit, page, r.PaginationLimit)
+}
+
+func (r *CategoryResource) Get(c *fiber.Ctx) error {
+	id := c.Params("id")
+	item, err := r.CRUD.GetByID(auth.Context(c), id)
+	if err != nil {
+		ferr := lookupError(err)
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	return response.SendFormatted(c, 200, item)
+}
+
+// Categories are curated: creating, updating and deleting them is restricted to admins.
+func (r *CategoryResource) Create(c *fiber.Ctx) error {
+	if err := r.Policy.Authorize(c, nil); err != nil {
+		return c.Status(err.Code).JSON(fiber.Map{"error": err.Message})
+	}
+
+	var item models.Category
+	if err := c.BodyParser(&item); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	item.Slug = slug.Make(item.Slug)
+	if item.Slug == "" {
+		item.Slug = slug.Make(item.Name)
+	}
+	if item.Slug == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "Category name is required"})
+	}
+
+	ctx := auth.Context(c)
+	if err := r.CRUD.Create(ctx, item); err != nil {
+		if isUniqueViolation(err) {
+			return c.Status(409).JSON(fiber.Map{"error": "A category with this slug already exists"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	created, err := r.CRUD.GetByID(ctx, item.Id)
+	if err != nil {
+		return response.SendFormatted(c, 201, item)
+	}
+
+	return response.SendFormatted(c, 201, created)
+}
+
+func (r *CategoryResource) Update(c *fiber.Ctx) error {
+	if err := r.Policy.Authorize(c, nil); err != nil {
+		return c.Status(err.Code).JSON(fiber.Map{"error": err.Message})
+	}
+
+	id := c.Params("id")
+	var item models.Category
+	if err := c.BodyParser(&item); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	item.Slug = slug.Make(item.Slug)
+	if item.Slug == "" {
+		item.Slug = slug.Make(item.Name)
+	}
+	if item.Name == "" || item.Slug == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "Category name is required"})
+	}
+
+	if err := r.CRUD.Update(auth.Context(c), id, item); err != nil {
+		if isUniqueViolation(err) {
+			return c.Status(409).JSON(fiber.Map{"error": "A category with this slug already exists"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return response.SendFormatted(c, 200, item)
+}
+
+func (r *CategoryResource) Delete(c *fiber.Ctx) error {
+	if err := r.Policy.Authorize(c, nil); err != nil {
+		return c.Status(err.Code).JSON(fiber.Map{"error": err.Message})
+	}
+
+	id := c.Params("id")
+	if err := r.CRUD.Delete(auth.Context(c), id); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.SendStatus(204)
+}
@@ -1,10 +1,17 @@
 package resources
 
 import (
+	"errors"
 	"net/url"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/nicolasbonnici/gorest-blog/hooks"
 	"github.com/nicolasbonnici/gorest-blog/models"
+	"github.com/nicolasbonnici/gorest-blog/moderation"
+	"github.com/nicolasbonnici/gorest-blog/policy"
+	"github.com/nicolasbonnici/gorest-blog/threads"
+	"github.com/nicolasbonnici/gorest-blog/trash"
+	"github.com/nicolasbonnici/gorest-blog/types"
 	"github.com/nicolasbonnici/gorest/crud"
 	"github.com/nicolasbonnici/gorest/database"
 	"github.com/nicolasbonnici/gorest/filter"
@@ -18,21 +25,65 @@ type CommentResource struct {
 	CRUD               *crud.CRUD[models.Comment]
 	PaginationLimit    int
 	PaginationMaxLimit int
+	Policy             *policy.Policy
+	Posts              *crud.CRUD[models.Post]
+	Threads            *threads.Store
+	Moderation         *moderation.Store
+	Trash              *trash.Store
 }
 
-func RegisterCommentRoutes(app *fiber.App, db database.Database, paginationLimit, maxPaginationLimit int) {
+type CommentTreeResponse struct {
+	PostId   string          `json:"postId"`
+	ParentId *string         `json:"parentId,omitempty"`
+	Items    []*threads.Node `json:"items"`
+	Total    int             `json:"total"`
+	Page     int             `json:"page"`
+	Limit    int             `json:"limit"`
+	Depth    int             `json:"depth"`
+}
+
+type ModerationQueueResponse struct {
+	Status types.CommentStatus `json:"status"`
+	Items  []models.Comment    `json:"items"`
+	Total  int                 `json:"total"`
+	Page   int                 `json:"page"`
+	Limit  int                 `json:"limit"`
+}
+
+type ModerationResponse struct {
+	Status  types.CommentStatus `json:"status"`
+	Updated []string            `json:"updated"`
+}
+
+func RegisterCommentRoutes(app *fiber.App, db database.Database, opts Options) {
 	res := &CommentResource{
-		DB:                 db,
-		CRUD:               crud.New[models.Comment](db),
-		PaginationLimit:    paginationLimit,
-		PaginationMaxLimit: maxPaginationLimit,
+		DB: db,
+		CRUD: crud.NewWithHooks[models.Comment](db, &hooks.CommentHooks{
+			DB:              db,
+			RequireApproval: opts.RequireCommentApproval,
+		}),
+		PaginationLimit:    opts.PaginationLimit,
+		PaginationMaxLimit: opts.PaginationMaxLimit,
+		Policy:             opts.Policy,
+		Posts:              crud.NewWithHooks[models.Post](db, &hooks.PostHooks{}),
+		Threads:            threads.NewStore(db),
+		Moderation:         moderation.NewStore(db),
+		Trash:              trash.NewStore(db),
 	}
 
+	app.Get("/posts/:id/comments/tree", res.Tree)
+	app.Get("/comments/moderation/queue", res.Queue)
+	app.Post("/comments/moderation/approve", res.moderate(types.CommentStatusApproved))
+	app.Post("/comments/moderation/reject", res.moderate(types.CommentStatusRejected))
+	app.Post("/comments/moderation/spam", res.moderate(types.CommentStatusSpam))
 	app.Get("/comments", res.List)
+	app.Get("/comments/trash", res.ListTrash)
+	app.Delete("/comments/trash/:id", res.Purge)
 	app.Get("/comments/:id", res.Get)
 	app.Post("/comments", res.Create)
 	app.Put("/comments/:id", res.Update)
 	app.Delete("/comments/:id", res.Delete)
+	app.Post("/comments/:id/restore", res.Restore)
 }
 
 func (r *CommentResource) List(c *fiber.Ctx) error {
@@ -44,7 +95,7 @@ func (r *CommentResource) List(c *fiber.Ctx) error {
 	offset := (page - 1) * limit
 	includeCount := c.Query("count", "true") != "false"
 
-	allowedFields := []string{"id", "user_id", "post_id", "parent_id", "content", "updated_at", "created_at"}
+	allowedFields := []string{"id", "user_id", "post_id", "parent_id", "content", "status", "like_count", "updated_at", "created_at"}
 
 	queryParams := make(url.Values)
 	c.Context().QueryArgs().VisitAll(func(key, value []byte) {
@@ -56,6 +107,8 @@ func (r *CommentResource) List(c *fiber.Ctx) error {
 		return pagination.SendPaginatedError(c, 400, err.Error())
 	}
 	whereClause, whereArgs := filters.BuildWhereClause()
+	whereClause, whereArgs = r.visibleOnly(c, whereClause, whereArgs)
+	whereClause, whereArgs = notTrashed(r.DB, whereClause, whereArgs)
 
 	ordering := filter.NewOrderSet(allowedFields)
 	if err := ordering.ParseFromQuery(queryParams); err != nil {
@@ -82,12 +135,78 @@ func (r *CommentResource) Get(c *fiber.Ctx) error {
 	id := c.Params("id")
 	item, err := r.CRUD.GetByID(auth.Context(c), id)
 	if err != nil {
+		ferr := lookupError(err)
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+	if !r.canView(c, item) || !r.postVisible(c, item.PostId) {
 		return c.Status(404).JSON(fiber.Map{"error": "Not found"})
 	}
 
 	return response.SendFormatted(c, 200, item)
 }
 
+// Tree returns the comments of a post as nested replies. Supported query parameters are
+// parent (root the tree at a comment instead of the post), depth, limit and page (applied
+// to the first level) and replies_limit (replies loaded per comment on deeper levels).
+func (r *CommentResource) Tree(c *fiber.Ctx) error {
+	ctx := r.Policy.Context(c)
+	post, err := r.Posts.GetByID(ctx, c.Params("id"))
+	if err != nil {
+		ferr := lookupError(err)
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	var parentID *string
+	if parent := c.Query("parent"); parent != "" {
+		belongs, err := r.Threads.BelongsToPost(ctx, parent, post.Id)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		if !belongs {
+			return c.Status(404).JSON(fiber.Map{"error": "Parent comment not found"})
+		}
+		parentID = &parent
+	}
+
+	depth := pagination.ParseIntQuery(c, "depth", threads.DefaultDepth, threads.MaxDepth)
+	if depth < 1 {
+		depth = 1
+	}
+	repliesLimit := pagination.ParseIntQuery(c, "replies_limit", threads.DefaultRepliesLimit, r.PaginationMaxLimit)
+	if repliesLimit < 0 {
+		repliesLimit = 0
+	}
+	limit := pagination.ParseIntQuery(c, "limit", r.PaginationLimit, r.PaginationMaxLimit)
+	page := pagination.ParseIntQuery(c, "page", 1, 10000)
+	if page < 1 {
+		page = 1
+	}
+
+	nodes, total, err := r.Threads.Tree(ctx, threads.Query{
+		PostID:       post.Id,
+		ParentID:     parentID,
+		Depth:        depth,
+		Limit:        limit,
+		Offset:       (page - 1) * limit,
+		RepliesLimit: repliesLimit,
+		ViewerID:     viewerID(c),
+		AllStatuses:  r.Policy.IsEditor(c),
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return response.SendFormatted(c, 200, CommentTreeResponse{
+		PostId:   post.Id,
+		ParentId: parentID,
+		Items:    nodes,
+		Total:    total,
+		Page:     page,
+		Limit:    limit,
+		Depth:    depth,
+	})
+}
+
 func (r *CommentResource) Create(c *fiber.Ctx) error {
 	var item models.Comment
 	if err := c.BodyParser(&item); err != nil {
@@ -98,7 +217,25 @@ func (r *CommentResource) Create(c *fiber.Ctx) error {
 		item.UserId = &user.UserID
 	}
 
+	if item.PostId == nil || *item.PostId == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "postId is required"})
+	}
+	// Comments go to posts their author can read: not to drafts of others nor to the trash.
+	if !r.postVisible(c, item.PostId) {
+		return c.Status(404).JSON(fiber.Map{"error": "Post not found"})
+	}
+
 	ctx := auth.Context(c)
+	if item.ParentId != nil {
+		belongs, err := r.Threads.BelongsToPost(ctx, *item.ParentId, *item.PostId)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		if !belongs {
+			return c.Status(400).JSON(fiber.Map{"error": "Parent comment does not belong to this post"})
+		}
+	}
+
 	if err := r.CRUD.Create(ctx, item); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -113,26 +250,252 @@ func (r *CommentResource) Create(c *fiber.Ctx) error {
 
 func (r *CommentResource) Update(c *fiber.Ctx) error {
 	id := c.Params("id")
+	ctx := auth.Context(c)
+
+	existing, err := r.CRUD.GetByID(ctx, id)
+	if err != nil {
+		ferr := lookupError(err)
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	if err := r.Policy.Authorize(c, existing.UserId); err != nil {
+		return c.Status(err.Code).JSON(fiber.Map{"error": err.Message})
+	}
+
 	var item models.Comment
 	if err := c.BodyParser(&item); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
 	}
 
-	if user := auth.GetAuthenticatedUser(c); user != nil {
-		item.UserId = &user.UserID
-	}
+	// A comment cannot be moved to another post or thread, and only moderators change its status.
+	item.UserId = existing.UserId
+	item.PostId = existing.PostId
+	item.ParentId = existing.ParentId
+	item.Status = existing.Status
+	item.LikeCount = existing.LikeCount
 
-	if err := r.CRUD.Update(auth.Context(c), id, item); err != nil {
+	if err := r.CRUD.Update(ctx, id, item); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
 
 	return response.SendFormatted(c, 200, item)
 }
 
+// Delete moves a comment to the trash. Its replies are kept.
 func (r *CommentResource) Delete(c *fiber.Ctx) error {
 	id := c.Params("id")
-	if err := r.CRUD.Delete(auth.Context(c), id); err != nil {
+	ctx := auth.Context(c)
+
+	existing, err := r.CRUD.GetByID(ctx, id)
+	if err != nil {
+		ferr := lookupError(err)
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	if err := r.Policy.Authorize(c, existing.UserId); err != nil {
+		return c.Status(err.Code).JSON(fiber.Map{"error": err.Message})
+	}
+
+	if err := r.Trash.Trash(ctx, trash.TargetComment, id); err != nil {
+		if errors.Is(err, trash.ErrNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "Not found"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.SendStatus(204)
+}
+
+// ListTrash lists the trashed comments of the authenticated user; admins see the whole trash.
+func (r *CommentResource) ListTrash(c *fiber.Ctx) error {
+	limit := pagination.ParseIntQuery(c, "limit", r.PaginationLimit, r.PaginationMaxLimit)
+	page := pagination.ParseIntQuery(c, "page", 1, 10000)
+	if page < 1 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+	includeCount := c.Query("count", "true") != "false"
+
+	allowedFields := []string{"id", "user_id", "post_id", "parent_id", "status", "updated_at", "created_at", "deleted_at"}
+
+	queryParams := make(url.Values)
+	c.Context().QueryArgs().VisitAll(func(key, value []byte) {
+		queryParams.Add(string(key), string(value))
+	})
+
+	filters := filter.NewFilterSet(allowedFields, r.DB.Dialect())
+	if err := filters.ParseFromQuery(queryParams); err != nil {
+		return pagination.SendPaginatedError(c, 400, err.Error())
+	}
+	whereClause, whereArgs := filters.BuildWhereClause()
+	whereClause, whereArgs, ok := trashedOnly(c, r.DB, r.Policy, whereClause, whereArgs)
+	if !ok {
+		return pagination.SendPaginatedError(c, 401, "Authentication required")
+	}
+
+	ordering := filter.NewOrderSet(allowedFields)
+	if err := ordering.ParseFromQuery(queryParams); err != nil {
+		return pagination.SendPaginatedError(c, 400, err.Error())
+	}
+	orderByClause := ordering.BuildOrderByClause()
+
+	result, err := r.CRUD.GetAllPaginated(auth.Context(c), crud.PaginationOptions{
+		Limit:         limit,
+		Offset:        offset,
+		IncludeCount:  includeCount,
+		WhereClause:   whereClause,
+		WhereArgs:     whereArgs,
+		OrderByClause: orderByClause,
+	})
+	if err != nil {
+		return pagination.SendPaginatedError(c, 500, err.Error())
+	}
+
+	return pagination.SendHydraCollection(c, result.Items, result.Total, limit, page, r.PaginationLimit)
+}
+
+// Restore takes a comment out of the trash (owner or admin).
+func (r *CommentResource) Restore(c *fiber.Ctx) error {
+	id := c.Params("id")
+	ctx := auth.Context(c)
+
+	if ferr := authorizeTrashed(c, r.Trash, r.Policy, trash.TargetComment, id); ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	if err := r.Trash.Restore(ctx, trash.TargetComment, id); err != nil {
+		if errors.Is(err, trash.ErrNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "Not found"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	restored, err := r.CRUD.GetByID(ctx, id)
+	if err != nil {
+		ferr := lookupError(err)
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+	return response.SendFormatted(c, 200, restored)
+}
+
+// Purge deletes a trashed comment for good, along with its replies (owner or admin).
+func (r *CommentResource) Purge(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	if ferr := authorizeTrashed(c, r.Trash, r.Policy, trash.TargetComment, id); ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	if err := r.Trash.Purge(auth.Context(c), trash.TargetComment, id); err != nil {
+		if errors.Is(err, trash.ErrNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "Not found"})
+		}
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
 	return c.SendStatus(204)
 }
+
+// Queue lists the comments awaiting moderation, oldest first. ?status= selects another
+// status (e.g. spam) to review. Editors and admins only.
+func (r *CommentResource) Queue(c *fiber.Ctx) error {
+	if err := r.Policy.AuthorizeEditor(c); err != nil {
+		return c.Status(err.Code).JSON(fiber.Map{"error": err.Message})
+	}
+
+	status := types.CommentStatus(c.Query("status", types.CommentStatusPending.String()))
+	if !status.IsValid() {
+		return c.Status(400).JSON(fiber.Map{"error": "Query parameter status must be pending, approved, rejected or spam"})
+	}
+
+	limit := pagination.ParseIntQuery(c, "limit", r.PaginationLimit, r.PaginationMaxLimit)
+	page := pagination.ParseIntQuery(c, "page", 1, 10000)
+	if page < 1 {
+		page = 1
+	}
+
+	comments, total, err := r.Moderation.Queue(auth.Context(c), status, limit, (page-1)*limit)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return response.SendFormatted(c, 200, ModerationQueueResponse{
+		Status: status,
+		Items:  comments,
+		Total:  total,
+		Page:   page,
+		Limit:  limit,
+	})
+}
+
+// moderate returns the handler moving the comments listed in the request body to status:
+// {"ids": ["..."]}. Editors and admins only.
+func (r *CommentResource) moderate(status types.CommentStatus) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := r.Policy.AuthorizeEditor(c); err != nil {
+			return c.Status(err.Code).JSON(fiber.Map{"error": err.Message})
+		}
+
+		var body struct {
+			Ids []string `json:"ids"`
+		}
+		if err := c.BodyParser(&body); err != nil || len(body.Ids) == 0 {
+			return c.Status(400).JSON(fiber.Map{"error": "Request body must list the comment ids"})
+		}
+
+		updated, err := r.Moderation.SetStatus(auth.Context(c), body.Ids, status)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return response.SendFormatted(c, 200, ModerationResponse{
+			Status:  status,
+			Updated: updated,
+		})
+	}
+}
+
+// visibleOnly restricts a comment where clause to what the caller may read: comments of
+// the posts visible to the caller that are not in the trash, and among them approved
+// comments and the caller's own comments. Editors and admins read comments of every
+// status, as they moderate them.
+func (r *CommentResource) visibleOnly(c *fiber.Ctx, clause string, args []any) (string, []any) {
+	post := "p.id = post_id AND p.deleted_at IS NULL"
+	cond, condArgs := r.Policy.Scope(c).Condition("p")
+	if cond != "" {
+		post += " AND " + cond
+	}
+	clause, args = andWhere(r.DB, clause, args, "EXISTS (SELECT 1 FROM post p WHERE "+post+")", condArgs...)
+
+	if r.Policy.IsEditor(c) {
+		return clause, args
+	}
+
+	approved := types.CommentStatusApproved.String()
+	if user := auth.GetAuthenticatedUser(c); user != nil {
+		return andWhere(r.DB, clause, args, "(status = ? OR user_id = ?)", approved, user.UserID)
+	}
+	return andWhere(r.DB, clause, args, "status = ?", approved)
+}
+
+// postVisible reports whether postID is a post the caller may read, outside the trash.
+func (r *CommentResource) postVisible(c *fiber.Ctx, postID *string) bool {
+	if postID == nil {
+		return false
+	}
+	_, err := r.Posts.GetByID(r.Policy.Context(c), *postID)
+	return err == nil
+}
+
+func (r *CommentResource) canView(c *fiber.Ctx, comment *models.Comment) bool {
+	if comment.Status == types.CommentStatusApproved.String() || r.Policy.IsEditor(c) {
+		return true
+	}
+	user := auth.GetAuthenticatedUser(c)
+	return user != nil && comment.UserId != nil && *comment.UserId == user.UserID
+}
+
+func viewerID(c *fiber.Ctx) string {
+	if user := auth.GetAuthenticatedUser(c); user != nil {
+		return user.UserID
+	}
+	return ""
+}
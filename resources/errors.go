@@ -0,0 +1,35 @@
+package resources
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// isUniqueViolation reports whether err was raised by a unique constraint of PostgreSQL
+// (SQLSTATE 23505), e.g. when two requests race for the same slug.
+func isUniqueViolation(err error) bool {
+	return err != nil && (strings.Contains(err.Error(), "SQLSTATE 23505") ||
+		strings.Contains(err.Error(), "duplicate key value violates unique constraint"))
+}
+
+// isNotFound reports whether err reports that no row matched a read by id, including ids
+// PostgreSQL cannot parse as a UUID (SQLSTATE 22P02).
+func isNotFound(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "no rows in result set") ||
+		strings.Contains(msg, "not found") ||
+		strings.Contains(msg, "SQLSTATE 22P02")
+}
+
+// lookupError returns the error answered when reading the resource to authorize fails:
+// 404 when it does not exist, 500 when the read itself failed.
+func lookupError(err error) *fiber.Error {
+	if isNotFound(err) {
+		return fiber.NewError(fiber.StatusNotFound, "Not found")
+	}
+	return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+}
@@ -0,0 +1,231 @@
+package resources
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nicolasbonnici/gorest-blog/feed"
+	"github.com/nicolasbonnici/gorest-blog/models"
+	"github.com/nicolasbonnici/gorest-blog/permalink"
+	"github.com/nicolasbonnici/gorest-blog/policy"
+	"github.com/nicolasbonnici/gorest-blog/render"
+	"github.com/nicolasbonnici/gorest-blog/visibility"
+	"github.com/nicolasbonnici/gorest/database"
+)
+
+type feedFormat struct {
+	contentType string
+	render      func(feed.Feed) ([]byte, error)
+}
+
+var feedFormats = map[string]feedFormat{
+	"rss":  {contentType: "application/rss+xml; charset=utf-8", render: feed.RSS},
+	"atom": {contentType: "application/atom+xml; charset=utf-8", render: feed.Atom},
+	"json": {contentType: "application/feed+json; charset=utf-8", render: feed.JSON},
+}
+
+type FeedResource struct {
+	DB              database.Database
+	ItemCount       int
+	SiteURL         string
+	SiteTitle       string
+	SiteDescription string
+	Renderer        *render.Renderer
+	Permalinks      *permalink.Builder
+	Policy          *policy.Policy
+}
+
+func RegisterFeedRoutes(app *fiber.App, db database.Database, opts Options) {
+	res := &FeedResource{
+		DB:              db,
+		ItemCount:       opts.FeedItemCount,
+		SiteURL:         strings.TrimRight(opts.SiteURL, "/"),
+		SiteTitle:       opts.SiteTitle,
+		SiteDescription: opts.SiteDescription,
+		Renderer:        opts.Renderer,
+		Permalinks:      opts.Permalinks,
+		Policy:          opts.Policy,
+	}
+
+	for ext, format := range feedFormats {
+		app.Get("/feed."+ext, res.Serve(format))
+		app.Get("/authors/:author/feed."+ext, res.Serve(format))
+		app.Get("/tags/:tag/feed."+ext, res.Serve(format))
+	}
+}
+
+// Serve returns a handler rendering the latest posts visible to the caller (published
+// posts for anonymous readers) in the given format, optionally restricted to the :author
+// (user id) or :tag (tag slug) route parameter.
+func (r *FeedResource) Serve(format feedFormat) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		author := c.Params("author")
+		tag := c.Params("tag")
+
+		scope := r.Policy.Scope(c)
+		items, err := r.latestItems(c.Context(), scope, author, tag)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		title := r.SiteTitle
+		switch {
+		case author != "" && len(items) > 0 && items[0].Author != "":
+			title = fmt.Sprintf("%s - %s", r.SiteTitle, items[0].Author)
+		case tag != "":
+			title = fmt.Sprintf("%s - #%s", r.SiteTitle, tag)
+		}
+
+		f := feed.Feed{
+			Title:       title,
+			Description: r.SiteDescription,
+			Link:        r.SiteURL + "/",
+			FeedURL:     r.SiteURL + c.Path(),
+			Items:       items,
+		}
+
+		body, err := format.render(f)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		sum := sha1.Sum(body)
+		etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+		lastModified := f.LastModified().UTC().Truncate(time.Second)
+
+		c.Set(fiber.HeaderETag, etag)
+		if !lastModified.IsZero() {
+			c.Set(fiber.HeaderLastModified, lastModified.Format(http.TimeFormat))
+		}
+		// Feeds including unpublished posts must not be stored by shared caches.
+		if scope.Public() {
+			c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+		} else {
+			c.Set(fiber.HeaderCacheControl, "private, max-age=300")
+		}
+
+		if notModified(c, etag, lastModified) {
+			return c.SendStatus(fiber.StatusNotModified)
+		}
+
+		c.Set(fiber.HeaderContentType, format.contentType)
+		return c.Status(200).Send(body)
+	}
+}
+
+func (r *FeedResource) latestItems(ctx context.Context, scope visibility.Scope, author, tag string) ([]feed.Item, error) {
+	where, args := andWhere(r.DB, "", nil, "p.deleted_at IS NULL")
+	if cond, condArgs := scope.Condition("p"); cond != "" {
+		where, args = andWhere(r.DB, where, args, cond, condArgs...)
+	}
+	if author != "" {
+		// Compared as text so that an author that is not a UUID matches no post.
+		where, args = andWhere(r.DB, where, args, "p.user_id::text = ?", author)
+	}
+	if tag != "" {
+		where, args = andWhere(r.DB, where, args,
+			"p.id IN (SELECT pt.post_id FROM post_tag pt JOIN tag t ON t.id = pt.tag_id WHERE t.slug = ?)", tag)
+	}
+	args = append(args, r.ItemCount)
+
+	query := fmt.Sprintf(`
+		SELECT p.id, p.slug, p.title, p.content,
+		       COALESCE(p.published_at, p.created_at),
+		       COALESCE(p.updated_at, p.published_at, p.created_at),
+		       COALESCE(TRIM(CONCAT(u.firstname, ' ', u.lastname)), '')
+		FROM post p
+		LEFT JOIN users u ON u.id = p.user_id
+		WHERE %s
+		ORDER BY p.published_at DESC NULLS LAST
+		LIMIT %s`, where, r.DB.Dialect().Placeholder(len(args)))
+
+	rows, err := r.DB.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query feed posts: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	items := make([]feed.Item, 0, r.ItemCount)
+	ids := make([]string, 0, r.ItemCount)
+	for rows.Next() {
+		var item feed.Item
+		var postSlug, content string
+		if err := rows.Scan(&item.ID, &postSlug, &item.Title, &content, &item.Published, &item.Updated, &item.Author); err != nil {
+			return nil, fmt.Errorf("failed to scan feed post: %w", err)
+		}
+		rendered := r.Renderer.Render(item.ID, content)
+		item.Content = rendered.Text
+		item.ContentHTML = rendered.HTML
+		item.Link = r.Permalinks.URL(models.Post{Id: item.ID, Slug: postSlug, PublishedAt: &item.Published})
+		items = append(items, item)
+		ids = append(ids, item.ID)
+	}
+
+	if len(ids) == 0 {
+		return items, nil
+	}
+
+	tags, err := r.tagsByPost(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range items {
+		items[i].Tags = tags[items[i].ID]
+	}
+
+	return items, nil
+}
+
+func (r *FeedResource) tagsByPost(ctx context.Context, postIDs []string) (map[string][]string, error) {
+	query := `
+		SELECT pt.post_id, t.name
+		FROM post_tag pt
+		JOIN tag t ON t.id = pt.tag_id
+		WHERE pt.post_id = ANY($1::uuid[])
+		ORDER BY t.name`
+
+	rows, err := r.DB.Query(ctx, query, postIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query feed tags: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	tags := make(map[string][]string, len(postIDs))
+	for rows.Next() {
+		var postID, name string
+		if err := rows.Scan(&postID, &name); err != nil {
+			return nil, fmt.Errorf("failed to scan feed tag: %w", err)
+		}
+		tags[postID] = append(tags[postID], name)
+	}
+
+	return tags, nil
+}
+
+// notModified evaluates the conditional request headers. If-None-Match takes
+// precedence over If-Modified-Since, as mandated by RFC 9110.
+func notModified(c *fiber.Ctx, etag string, lastModified time.Time) bool {
+	if match := c.Get(fiber.HeaderIfNoneMatch); match != "" {
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == "*" || candidate == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if since := c.Get(fiber.HeaderIfModifiedSince); since != "" && !lastModified.IsZero() {
+		if t, err := http.ParseTime(since); err == nil {
+			return !lastModified.After(t)
+		}
+	}
+
+	return false
+}
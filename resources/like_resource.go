@@ -1,10 +1,14 @@
 package resources
 
 import (
+	"errors"
 	"net/url"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/nicolasbonnici/gorest-blog/likes"
 	"github.com/nicolasbonnici/gorest-blog/models"
+	"github.com/nicolasbonnici/gorest-blog/policy"
+	"github.com/nicolasbonnici/gorest-blog/types"
 	"github.com/nicolasbonnici/gorest/crud"
 	"github.com/nicolasbonnici/gorest/database"
 	"github.com/nicolasbonnici/gorest/filter"
@@ -18,16 +22,28 @@ type LikeResource struct {
 	CRUD               *crud.CRUD[models.Like]
 	PaginationLimit    int
 	PaginationMaxLimit int
+	Policy             *policy.Policy
+	Likes              *likes.Store
 }
 
-func RegisterLikeRoutes(app *fiber.App, db database.Database, paginationLimit, maxPaginationLimit int) {
+func RegisterLikeRoutes(app *fiber.App, db database.Database, opts Options) {
 	res := &LikeResource{
 		DB:                 db,
 		CRUD:               crud.New[models.Like](db),
-		PaginationLimit:    paginationLimit,
-		PaginationMaxLimit: maxPaginationLimit,
+		PaginationLimit:    opts.PaginationLimit,
+		PaginationMaxLimit: opts.PaginationMaxLimit,
+		Policy:             opts.Policy,
+		Likes:              likes.NewStore(db, opts.ReactionKinds),
 	}
 
+	for _, likeable := range []string{likes.TargetPost, likes.TargetComment} {
+		prefix := "/" + likeable + "s/:id"
+		app.Put(prefix+"/like", res.react(likeable, true))
+		app.Delete(prefix+"/like", res.react(likeable, false))
+		app.Get(prefix+"/reactions", res.Reactions(likeable))
+		app.Put(prefix+"/reactions/:kind", res.react(likeable, true))
+		app.Delete(prefix+"/reactions/:kind", res.react(likeable, false))
+	}
 	app.Get("/likes", res.List)
 	app.Get("/likes/:id", res.Get)
 	app.Post("/likes", res.Create)
@@ -44,7 +60,7 @@ func (r *LikeResource) List(c *fiber.Ctx) error {
 	offset := (page - 1) * limit
 	includeCount := c.Query("count", "true") != "false"
 
-	allowedFields := []string{"id", "liker_id", "liked_id", "likeable", "likeable_id", "liked_at", "updated_at", "created_at"}
+	allowedFields := []string{"id", "liker_id", "liked_id", "likeable", "likeable_id", "kind", "liked_at", "updated_at", "created_at"}
 
 	queryParams := make(url.Values)
 	c.Context().QueryArgs().VisitAll(func(key, value []byte) {
@@ -56,6 +72,7 @@ func (r *LikeResource) List(c *fiber.Ctx) error {
 		return pagination.SendPaginatedError(c, 400, err.Error())
 	}
 	whereClause, whereArgs := filters.BuildWhereClause()
+	whereClause, whereArgs = r.visibleOnly(c, whereClause, whereArgs)
 
 	ordering := filter.NewOrderSet(allowedFields)
 	if err := ordering.ParseFromQuery(queryParams); err != nil {
@@ -78,34 +95,71 @@ func (r *LikeResource) List(c *fiber.Ctx) error {
 	return pagination.SendHydraCollection(c, result.Items, result.Total, limit, page, r.PaginationLimit)
 }
 
+// Get returns a like when its target is visible to the caller, see likes.Store.Exists.
 func (r *LikeResource) Get(c *fiber.Ctx) error {
 	id := c.Params("id")
-	item, err := r.CRUD.GetByID(auth.Context(c), id)
+	ctx := auth.Context(c)
+	item, err := r.CRUD.GetByID(ctx, id)
 	if err != nil {
+		ferr := lookupError(err)
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	visible, err := r.Likes.Exists(ctx, r.Policy.Scope(c), item.Likeable, item.LikeableId)
+	if err != nil && !errors.Is(err, likes.ErrUnknownTarget) {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	if !visible {
 		return c.Status(404).JSON(fiber.Map{"error": "Not found"})
 	}
 
 	return response.SendFormatted(c, 200, item)
 }
 
+// Create reacts on the target given by likeable and likeableId for the authenticated user,
+// with the given kind or the default one. The liked user and the like date are derived
+// server-side; reacting twice with the same kind is a 409.
 func (r *LikeResource) Create(c *fiber.Ctx) error {
+	user := auth.GetAuthenticatedUser(c)
+	if user == nil {
+		return c.Status(401).JSON(fiber.Map{"error": "Authentication required"})
+	}
+
 	var item models.Like
 	if err := c.BodyParser(&item); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
 	}
 
-	if user := auth.GetAuthenticatedUser(c); user != nil {
-		item.LikerId = &user.UserID
+	if item.Kind == "" {
+		item.Kind = r.Likes.DefaultKind()
+	}
+	if !r.Likes.IsKind(item.Kind) {
+		return c.Status(400).JSON(fiber.Map{"error": likes.ErrUnknownKind.Error()})
 	}
 
 	ctx := auth.Context(c)
-	if err := r.CRUD.Create(ctx, item); err != nil {
+	exists, err := r.Likes.Exists(ctx, r.Policy.Scope(c), item.Likeable, item.LikeableId)
+	if errors.Is(err, likes.ErrUnknownTarget) {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	if !exists {
+		return c.Status(404).JSON(fiber.Map{"error": "Liked " + item.Likeable + " not found"})
+	}
+
+	likeID, inserted, err := r.Likes.Add(ctx, r.Policy.Scope(c), user.UserID, item.Likeable, item.LikeableId, item.Kind)
+	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
+	if !inserted {
+		return c.Status(409).JSON(fiber.Map{"error": "Already liked"})
+	}
 
-	created, err := r.CRUD.GetByID(ctx, item.Id)
+	created, err := r.CRUD.GetByID(ctx, likeID)
 	if err != nil {
-		return response.SendFormatted(c, 201, item)
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
 
 	return response.SendFormatted(c, 201, created)
@@ -113,16 +167,32 @@ func (r *LikeResource) Create(c *fiber.Ctx) error {
 
 func (r *LikeResource) Update(c *fiber.Ctx) error {
 	id := c.Params("id")
+	ctx := auth.Context(c)
+
+	existing, err := r.CRUD.GetByID(ctx, id)
+	if err != nil {
+		ferr := lookupError(err)
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	if err := r.Policy.Authorize(c, existing.LikerId); err != nil {
+		return c.Status(err.Code).JSON(fiber.Map{"error": err.Message})
+	}
+
 	var item models.Like
 	if err := c.BodyParser(&item); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
 	}
 
-	if user := auth.GetAuthenticatedUser(c); user != nil {
-		item.LikerId = &user.UserID
-	}
+	// The target of a like and its date are fixed once created.
+	item.LikerId = existing.LikerId
+	item.LikedId = existing.LikedId
+	item.Likeable = existing.Likeable
+	item.LikeableId = existing.LikeableId
+	item.Kind = existing.Kind
+	item.LikedAt = existing.LikedAt
 
-	if err := r.CRUD.Update(auth.Context(c), id, item); err != nil {
+	if err := r.CRUD.Update(ctx, id, item); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
 
@@ -131,8 +201,113 @@ func (r *LikeResource) Update(c *fiber.Ctx) error {
 
 func (r *LikeResource) Delete(c *fiber.Ctx) error {
 	id := c.Params("id")
-	if err := r.CRUD.Delete(auth.Context(c), id); err != nil {
+	ctx := auth.Context(c)
+
+	existing, err := r.CRUD.GetByID(ctx, id)
+	if err != nil {
+		ferr := lookupError(err)
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	if err := r.Policy.Authorize(c, existing.LikerId); err != nil {
+		return c.Status(err.Code).JSON(fiber.Map{"error": err.Message})
+	}
+
+	if err := r.CRUD.Delete(ctx, id); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
 	return c.SendStatus(204)
 }
+
+// react returns the handler adding (liked) or removing the reaction of the authenticated
+// user on the :id target. The kind is the :kind route parameter, or the default kind on the
+// /like routes. Both operations are idempotent; the response is the resulting likes.State.
+func (r *LikeResource) react(likeable string, liked bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user := auth.GetAuthenticatedUser(c)
+		if user == nil {
+			return c.Status(401).JSON(fiber.Map{"error": "Authentication required"})
+		}
+
+		kind := c.Params("kind", r.Likes.DefaultKind())
+		if !r.Likes.IsKind(kind) {
+			return c.Status(400).JSON(fiber.Map{"error": likes.ErrUnknownKind.Error()})
+		}
+
+		ctx := auth.Context(c)
+		id := c.Params("id")
+
+		exists, err := r.Likes.Exists(ctx, r.Policy.Scope(c), likeable, id)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		if !exists {
+			return c.Status(404).JSON(fiber.Map{"error": "Not found"})
+		}
+
+		if liked {
+			_, _, err = r.Likes.Add(ctx, r.Policy.Scope(c), user.UserID, likeable, id, kind)
+		} else {
+			err = r.Likes.Remove(ctx, user.UserID, likeable, id, kind)
+		}
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		state, err := r.Likes.State(ctx, user.UserID, likeable, id, kind)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return response.SendFormatted(c, 200, state)
+	}
+}
+
+// Reactions returns the handler counting the reactions per kind on the :id target.
+func (r *LikeResource) Reactions(likeable string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := auth.Context(c)
+		id := c.Params("id")
+
+		exists, err := r.Likes.Exists(ctx, r.Policy.Scope(c), likeable, id)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		if !exists {
+			return c.Status(404).JSON(fiber.Map{"error": "Not found"})
+		}
+
+		viewerID := ""
+		if user := auth.GetAuthenticatedUser(c); user != nil {
+			viewerID = user.UserID
+		}
+
+		reactions, err := r.Likes.Reactions(ctx, r.Policy.Scope(c), viewerID, likeable, id)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return response.SendFormatted(c, 200, reactions)
+	}
+}
+
+// visibleOnly restricts a like where clause to the likes whose target is visible to the
+// caller: posts outside the trash in the caller's visibility scope, and approved comments
+// of such posts, as likes.Store does for reactions.
+func (r *LikeResource) visibleOnly(c *fiber.Ctx, clause string, args []any) (string, []any) {
+	post := "p.deleted_at IS NULL"
+	cond, condArgs := r.Policy.Scope(c).Condition("p")
+	if cond != "" {
+		post += " AND " + cond
+	}
+
+	visible := "(likeable = ? AND EXISTS (SELECT 1 FROM post p WHERE p.id = likeable_id AND " + post + "))" +
+		" OR (likeable = ? AND EXISTS (SELECT 1 FROM comment cm JOIN post p ON p.id = cm.post_id" +
+		" WHERE cm.id = likeable_id AND cm.deleted_at IS NULL AND cm.status = ? AND " + post + "))"
+
+	visibleArgs := append([]any{likes.TargetPost}, condArgs...)
+	visibleArgs = append(visibleArgs, likes.TargetComment, types.CommentStatusApproved.String())
+	visibleArgs = append(visibleArgs, condArgs...)
+
+	return andWhere(r.DB, clause, args, "("+visible+")", visibleArgs...)
+}
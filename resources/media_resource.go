@@ -0,0 +1,215 @@
+package resources
+
+import (
+	"errors"
+	"io"
+	"net/url"
+
+	"github.com/gofiber/fiber/v2"
+	auth "github.com/nicolasbonnici/gorest-auth"
+	"github.com/nicolasbonnici/gorest-blog/media"
+	"github.com/nicolasbonnici/gorest-blog/models"
+	"github.com/nicolasbonnici/gorest-blog/policy"
+	"github.com/nicolasbonnici/gorest/crud"
+	"github.com/nicolasbonnici/gorest/database"
+	"github.com/nicolasbonnici/gorest/filter"
+	"github.com/nicolasbonnici/gorest/pagination"
+	"github.com/nicolasbonnici/gorest/response"
+)
+
+type MediaResource struct {
+	DB                 database.Database
+	CRUD               *crud.CRUD[models.Media]
+	PaginationLimit    int
+	PaginationMaxLimit int
+	Policy             *policy.Policy
+	Media              *media.Store
+}
+
+// MediaItem is a media record along with the public URL of its file.
+type MediaItem struct {
+	models.Media
+	URL string `json:"url"`
+}
+
+func RegisterMediaRoutes(app *fiber.App, db database.Database, opts Options) {
+	res := &MediaResource{
+		DB:                 db,
+		CRUD:               crud.New[models.Media](db),
+		PaginationLimit:    opts.PaginationLimit,
+		PaginationMaxLimit: opts.PaginationMaxLimit,
+		Policy:             opts.Policy,
+		Media:              opts.Media,
+	}
+
+	app.Get(media.FilesPath+"*", res.Serve)
+	app.Get("/media", res.List)
+	app.Get("/media/:id", res.Get)
+	app.Post("/media", res.Upload)
+	app.Delete("/media/:id", res.Delete)
+}
+
+// List returns the media uploaded by the authenticated user; admins see every upload.
+func (r *MediaResource) List(c *fiber.Ctx) error {
+	user := auth.GetAuthenticatedUser(c)
+	if user == nil {
+		return pagination.SendPaginatedError(c, 401, "Authentication required")
+	}
+
+	limit := pagination.ParseIntQuery(c, "limit", r.PaginationLimit, r.PaginationMaxLimit)
+	page := pagination.ParseIntQuery(c, "page", 1, 10000)
+	if page < 1 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+	includeCount := c.Query("count", "true") != "false"
+
+	allowedFields := []string{"id", "user_id", "filename", "content_type", "size", "created_at"}
+
+	queryParams := make(url.Values)
+	c.Context().QueryArgs().VisitAll(func(key, value []byte) {
+		queryParams.Add(string(key), string(value))
+	})
+
+	filters := filter.NewFilterSet(allowedFields, r.DB.Dialect())
+	if err := filters.ParseFromQuery(queryParams); err != nil {
+		return pagination.SendPaginatedError(c, 400, err.Error())
+	}
+	whereClause, whereArgs := filters.BuildWhereClause()
+	if !r.Policy.IsAdmin(c) {
+		whereClause, whereArgs = andWhere(r.DB, whereClause, whereArgs, "user_id = ?", user.UserID)
+	}
+
+	ordering := filter.NewOrderSet(allowedFields)
+	if err := ordering.ParseFromQuery(queryParams); err != nil {
+		return pagination.SendPaginatedError(c, 400, err.Error())
+	}
+	orderByClause := ordering.BuildOrderByClause()
+
+	result, err := r.CRUD.GetAllPaginated(auth.Context(c), crud.PaginationOptions{
+		Limit:         limit,
+		Offset:        offset,
+		IncludeCount:  includeCount,
+		WhereClause:   whereClause,
+		WhereArgs:     whereArgs,
+		OrderByClause: orderByClause,
+	})
+	if err != nil {
+		return pagination.SendPaginatedError(c, 500, err.Error())
+	}
+
+	items := make([]MediaItem, 0, len(result.Items))
+	for i := range result.Items {
+		items = append(items, r.item(&result.Items[i]))
+	}
+
+	return pagination.SendHydraCollection(c, items, result.Total, limit, page, r.PaginationLimit)
+}
+
+// Get returns a media uploaded by the authenticated user; admins may read every upload.
+func (r *MediaResource) Get(c *fiber.Ctx) error {
+	item, err := r.Media.Get(auth.Context(c), c.Params("id"))
+	if errors.Is(err, media.ErrNotFound) {
+		return c.Status(404).JSON(fiber.Map{"error": "Not found"})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if ferr := r.Policy.Authorize(c, item.UserId); ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	return response.SendFormatted(c, 200, r.item(item))
+}
+
+// Upload stores the image sent as the "file" field of a multipart form.
+func (r *MediaResource) Upload(c *fiber.Ctx) error {
+	user := auth.GetAuthenticatedUser(c)
+	if user == nil {
+		return c.Status(401).JSON(fiber.Map{"error": "Authentication required"})
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "A multipart \"file\" field is required"})
+	}
+	if header.Size > r.Media.MaxSize() {
+		return c.Status(413).JSON(fiber.Map{"error": media.ErrTooLarge.Error()})
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	defer func() { _ = file.Close() }()
+
+	item, err := r.Media.Upload(auth.Context(c), user.UserID, header.Filename, file)
+	switch {
+	case errors.Is(err, media.ErrTooLarge):
+		return c.Status(413).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, media.ErrUnsupportedType):
+		return c.Status(415).JSON(fiber.Map{"error": err.Error()})
+	case err != nil:
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return response.SendFormatted(c, 201, r.item(item))
+}
+
+// Delete removes a media (owner or admin). Posts using it as cover lose their cover.
+func (r *MediaResource) Delete(c *fiber.Ctx) error {
+	ctx := auth.Context(c)
+	item, err := r.Media.Get(ctx, c.Params("id"))
+	if errors.Is(err, media.ErrNotFound) {
+		return c.Status(404).JSON(fiber.Map{"error": "Not found"})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if ferr := r.Policy.Authorize(c, item.UserId); ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	if err := r.Media.Delete(ctx, item); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.SendStatus(204)
+}
+
+// Serve streams a stored file. Storage keys are random and never reused, so files are
+// cached for a long time.
+func (r *MediaResource) Serve(c *fiber.Ctx) error {
+	ctx := auth.Context(c)
+	item, err := r.Media.GetByKey(ctx, c.Params("*"))
+	if errors.Is(err, media.ErrNotFound) {
+		return c.Status(404).JSON(fiber.Map{"error": "Not found"})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	file, err := r.Media.Open(ctx, item)
+	if errors.Is(err, media.ErrFileNotFound) {
+		return c.Status(404).JSON(fiber.Map{"error": "Not found"})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	defer func() { _ = file.Close() }()
+
+	body, err := io.ReadAll(file)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	c.Set(fiber.HeaderContentType, item.ContentType)
+	c.Set(fiber.HeaderCacheControl, "public, max-age=31536000, immutable")
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	return c.Status(200).Send(body)
+}
+
+func (r *MediaResource) item(m *models.Media) MediaItem {
+	return MediaItem{Media: *m, URL: r.Media.URL(m)}
+}
@@ -0,0 +1,34 @@
+package resources
+
+import (
+	"github.com/nicolasbonnici/gorest-blog/media"
+	"github.com/nicolasbonnici/gorest-blog/permalink"
+	"github.com/nicolasbonnici/gorest-blog/policy"
+	"github.com/nicolasbonnici/gorest-blog/render"
+	"github.com/nicolasbonnici/gorest-blog/sitemap"
+	"github.com/nicolasbonnici/gorest-blog/slug"
+)
+
+// Options holds the settings shared by every blog resource.
+type Options struct {
+	PaginationLimit    int
+	PaginationMaxLimit int
+	Policy             *policy.Policy
+	FeedItemCount      int
+	SiteURL            string
+	SiteTitle          string
+	SiteDescription    string
+	SlugScope          slug.Scope
+	// RequireCommentApproval holds new comments for moderation unless their post overrides it.
+	RequireCommentApproval bool
+	// ReactionKinds lists the accepted like kinds, the first one being the default.
+	ReactionKinds []string
+	// Renderer turns post markdown into sanitized HTML; it is shared so its cache is too.
+	Renderer *render.Renderer
+	// Media stores uploads and post cover images.
+	Media *media.Store
+	// Permalinks builds the public URLs of post pages, used by feeds, metadata and the sitemap.
+	Permalinks *permalink.Builder
+	// Sitemap is invalidated by every change to a post.
+	Sitemap *sitemap.Sitemap
+}
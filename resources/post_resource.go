@@ -2,12 +2,23 @@ package resources
 
 import (
 	"context"
-	"fmt"
+	"encoding/json"
+	"errors"
 	"net/url"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/nicolasbonnici/gorest-blog/hooks"
+	"github.com/nicolasbonnici/gorest-blog/media"
 	"github.com/nicolasbonnici/gorest-blog/models"
+	"github.com/nicolasbonnici/gorest-blog/permalink"
+	"github.com/nicolasbonnici/gorest-blog/policy"
+	"github.com/nicolasbonnici/gorest-blog/render"
+	"github.com/nicolasbonnici/gorest-blog/revisions"
+	"github.com/nicolasbonnici/gorest-blog/seo"
+	"github.com/nicolasbonnici/gorest-blog/sitemap"
+	"github.com/nicolasbonnici/gorest-blog/slug"
+	"github.com/nicolasbonnici/gorest-blog/taxonomy"
+	"github.com/nicolasbonnici/gorest-blog/trash"
 	"github.com/nicolasbonnici/gorest/crud"
 	"github.com/nicolasbonnici/gorest/database"
 	"github.com/nicolasbonnici/gorest/filter"
@@ -21,23 +32,65 @@ type PostResource struct {
 	CRUD               *crud.CRUD[models.Post]
 	PaginationLimit    int
 	PaginationMaxLimit int
+	Policy             *policy.Policy
+	Taxonomy           *taxonomy.Store
+	Revisions          *revisions.Store
+	Slugs              *slug.Registry
+	Renderer           *render.Renderer
+	Media              *media.Store
+	Permalinks         *permalink.Builder
+	Sitemap            *sitemap.Sitemap
+	Trash              *trash.Store
+	SiteTitle          string
 }
 
-func RegisterPostRoutes(app *fiber.App, db database.Database, paginationLimit, maxPaginationLimit int) {
+// RenderedPost is a post along with its content rendered as sanitized HTML, returned
+// when ?format=html is requested.
+type RenderedPost struct {
+	models.Post
+	ContentHTML string `json:"contentHtml"`
+}
+
+var (
+	ErrPostNotFound      = errors.New("post not found")
+	errSlugRequired      = errors.New("a slug or a title is required")
+	errUnknownCoverImage = errors.New("coverImageId does not reference a media uploaded by the post author")
+)
+
+func RegisterPostRoutes(app *fiber.App, db database.Database, opts Options) {
 	postHooks := &hooks.PostHooks{}
 
 	res := &PostResource{
 		DB:                 db,
 		CRUD:               crud.NewWithHooks[models.Post](db, postHooks),
-		PaginationLimit:    paginationLimit,
-		PaginationMaxLimit: maxPaginationLimit,
+		PaginationLimit:    opts.PaginationLimit,
+		PaginationMaxLimit: opts.PaginationMaxLimit,
+		Policy:             opts.Policy,
+		Taxonomy:           taxonomy.NewStore(db),
+		Revisions:          revisions.NewStore(db),
+		Slugs:              slug.NewRegistry(db, opts.SlugScope),
+		Renderer:           opts.Renderer,
+		Media:              opts.Media,
+		Permalinks:         opts.Permalinks,
+		Sitemap:            opts.Sitemap,
+		Trash:              trash.NewStore(db),
+		SiteTitle:          opts.SiteTitle,
 	}
 
 	app.Get("/posts", res.List)
+	app.Get("/posts/trash", res.ListTrash)
+	app.Delete("/posts/trash/:id", res.Purge)
+	app.Get("/posts/by-slug/:slug", res.GetBySlug)
 	app.Get("/posts/:id", res.Get)
 	app.Post("/posts", res.Create)
 	app.Put("/posts/:id", res.Update)
 	app.Delete("/posts/:id", res.Delete)
+	app.Post("/posts/:id/restore", res.Restore)
+	app.Get("/posts/:id/meta", res.GetMeta)
+	app.Get("/posts/:id/tags", res.GetTags)
+	app.Put("/posts/:id/tags", res.SetTags)
+	app.Get("/posts/:id/categories", res.GetCategories)
+	app.Put("/posts/:id/categories", res.SetCategories)
 }
 
 func (r *PostResource) List(c *fiber.Ctx) error {
@@ -49,18 +102,35 @@ func (r *PostResource) List(c *fiber.Ctx) error {
 	offset := (page - 1) * limit
 	includeCount := c.Query("count", "true") != "false"
 
-	allowedFields := []string{"id", "user_id", "slug", "status", "title", "content", "published_at", "updated_at", "created_at"}
+	allowedFields := []string{"id", "user_id", "slug", "status", "title", "content", "published_at", "like_count", "comment_count", "word_count", "reading_time_minutes", "cover_image_id", "updated_at", "created_at"}
 
 	queryParams := make(url.Values)
 	c.Context().QueryArgs().VisitAll(func(key, value []byte) {
 		queryParams.Add(string(key), string(value))
 	})
 
+	tagSlugs := queryParams["tag"]
+	categorySlugs := queryParams["category"]
+	queryParams.Del("tag")
+	queryParams.Del("category")
+	queryParams.Del("format")
+
 	filters := filter.NewFilterSet(allowedFields, r.DB.Dialect())
 	if err := filters.ParseFromQuery(queryParams); err != nil {
 		return pagination.SendPaginatedError(c, 400, err.Error())
 	}
 	whereClause, whereArgs := filters.BuildWhereClause()
+	whereClause, whereArgs = notTrashed(r.DB, whereClause, whereArgs)
+	whereClause, whereArgs = r.visibleOnly(c, whereClause, whereArgs)
+
+	if len(tagSlugs) > 0 {
+		whereClause, whereArgs = andWhere(r.DB, whereClause, whereArgs,
+			"id IN (SELECT pt.post_id FROM post_tag pt JOIN tag t ON t.id = pt.tag_id WHERE t.slug = ANY(?))", tagSlugs)
+	}
+	if len(categorySlugs) > 0 {
+		whereClause, whereArgs = andWhere(r.DB, whereClause, whereArgs,
+			"id IN (SELECT pc.post_id FROM post_category pc JOIN category ca ON ca.id = pc.category_id WHERE ca.slug = ANY(?))", categorySlugs)
+	}
 
 	ordering := filter.NewOrderSet(allowedFields)
 	if err := ordering.ParseFromQuery(queryParams); err != nil {
@@ -68,7 +138,7 @@ func (r *PostResource) List(c *fiber.Ctx) error {
 	}
 	orderByClause := ordering.BuildOrderByClause()
 
-	result, err := r.CRUD.GetAllPaginated(auth.Context(c), crud.PaginationOptions{
+	result, err := r.CRUD.GetAllPaginated(r.Policy.Context(c), crud.PaginationOptions{
 		Limit:         limit,
 		Offset:        offset,
 		IncludeCount:  includeCount,
@@ -80,19 +150,87 @@ func (r *PostResource) List(c *fiber.Ctx) error {
 		return pagination.SendPaginatedError(c, 500, err.Error())
 	}
 
+	if wantsHTML(c) {
+		rendered := make([]RenderedPost, 0, len(result.Items))
+		for _, item := range result.Items {
+			rendered = append(rendered, r.render(item))
+		}
+		return pagination.SendHydraCollection(c, rendered, result.Total, limit, page, r.PaginationLimit)
+	}
+
 	return pagination.SendHydraCollection(c, result.Items, result.Total, limit, page, r.PaginationLimit)
 }
 
 func (r *PostResource) Get(c *fiber.Ctx) error {
 	id := c.Params("id")
-	item, err := r.CRUD.GetByID(auth.Context(c), id)
+	item, err := r.CRUD.GetByID(r.Policy.Context(c), id)
 	if err != nil {
-		return c.Status(404).JSON(fiber.Map{"error": "Not found"})
+		ferr := lookupError(err)
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
 	}
 
+	if wantsHTML(c) {
+		return response.SendFormatted(c, 200, r.render(*item))
+	}
 	return response.SendFormatted(c, 200, item)
 }
 
+// GetBySlug returns the post reachable under :slug. ?author= (user id) picks one post when
+// slugs are only unique per author. A former slug redirects permanently to the current one.
+func (r *PostResource) GetBySlug(c *fiber.Ctx) error {
+	ctx := r.Policy.Context(c)
+	postSlug := c.Params("slug")
+	author := c.Query("author")
+
+	where, args := andWhere(r.DB, "", nil, "slug = ?", postSlug)
+	where, args = notTrashed(r.DB, where, args)
+	where, args = r.visibleOnly(c, where, args)
+	if author != "" {
+		where, args = andWhere(r.DB, where, args, "user_id::text = ?", author)
+	}
+
+	result, err := r.CRUD.GetAllPaginated(ctx, crud.PaginationOptions{
+		Limit:       2,
+		WhereClause: where,
+		WhereArgs:   args,
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	switch len(result.Items) {
+	case 1:
+		if wantsHTML(c) {
+			return response.SendFormatted(c, 200, r.render(result.Items[0]))
+		}
+		return response.SendFormatted(c, 200, result.Items[0])
+	case 2:
+		return c.Status(409).JSON(fiber.Map{"error": "Several authors use this slug, pass ?author= to choose one"})
+	}
+
+	current, found, err := r.Slugs.Resolve(ctx, r.Policy.Scope(c), postSlug, author)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	if !found || current == postSlug {
+		return c.Status(404).JSON(fiber.Map{"error": "Not found"})
+	}
+
+	location := "/posts/by-slug/" + url.PathEscape(current)
+	query := url.Values{}
+	if author != "" {
+		query.Set("author", author)
+	}
+	if format := c.Query("format"); format != "" {
+		query.Set("format", format)
+	}
+	if len(query) > 0 {
+		location += "?" + query.Encode()
+	}
+
+	return c.Redirect(location, fiber.StatusMovedPermanently)
+}
+
 func (r *PostResource) Create(c *fiber.Ctx) error {
 	var item models.Post
 	if err := c.BodyParser(&item); err != nil {
@@ -103,10 +241,34 @@ func (r *PostResource) Create(c *fiber.Ctx) error {
 		item.UserId = &user.UserID
 	}
 
-	ctx := auth.Context(c)
+	ctx := r.Policy.Context(c)
+	if err := r.assignSlug(ctx, &item, ""); err != nil {
+		if errors.Is(err, errSlugRequired) {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := seo.Normalize(&item); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := r.checkCoverImage(c, &item); err != nil {
+		if errors.Is(err, errUnknownCoverImage) {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
 	if err := r.CRUD.Create(ctx, item); err != nil {
+		if isUniqueViolation(err) {
+			return c.Status(409).JSON(fiber.Map{"error": "The slug was just taken, retry the request"})
+		}
+		if errors.Is(err, hooks.ErrPublishedAtRequired) {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
+	r.Sitemap.Invalidate()
 
 	created, err := r.CRUD.GetByID(ctx, item.Id)
 	if err != nil {
@@ -118,49 +280,443 @@ func (r *PostResource) Create(c *fiber.Ctx) error {
 
 func (r *PostResource) Update(c *fiber.Ctx) error {
 	id := c.Params("id")
+	ctx := r.Policy.Context(c)
+
+	existing, err := r.CRUD.GetByID(ctx, id)
+	if err != nil {
+		ferr := lookupError(err)
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	if err := r.Policy.Authorize(c, existing.UserId); err != nil {
+		return c.Status(err.Code).JSON(fiber.Map{"error": err.Message})
+	}
+
 	var item models.Post
 	if err := c.BodyParser(&item); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
 	}
 
-	if user := auth.GetAuthenticatedUser(c); user != nil {
-		item.UserId = &user.UserID
+	// Ownership never changes through an update, even when an admin edits the row.
+	// Counters are maintained by the database and are only echoed back.
+	item.UserId = existing.UserId
+	item.SlugScope = existing.SlugScope
+	item.LikeCount = existing.LikeCount
+	item.CommentCount = existing.CommentCount
+	// Optional fields are kept unless a new value, or an empty one to drop them, is sent.
+	keepUnsent(&item.ExcerptOverride, existing.ExcerptOverride)
+	keepUnsent(&item.CoverImageId, existing.CoverImageId)
+	keepUnsent(&item.CanonicalUrl, existing.CanonicalUrl)
+	keepUnsent(&item.MetaTitle, existing.MetaTitle)
+	keepUnsent(&item.MetaDescription, existing.MetaDescription)
+	keepUnsent(&item.OgTitle, existing.OgTitle)
+	keepUnsent(&item.OgDescription, existing.OgDescription)
+	keepUnsent(&item.OgImageUrl, existing.OgImageUrl)
+	keepUnsent(&item.PublishedAt, existing.PublishedAt)
+	// The comment approval override has no empty value: an explicit null clears it.
+	if !sentNull(c.Body(), "requireCommentApproval") {
+		keepUnsent(&item.RequireCommentApproval, existing.RequireCommentApproval)
+	}
+
+	if err := seo.Normalize(&item); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := r.checkCoverImage(c, &item); err != nil {
+		if errors.Is(err, errUnknownCoverImage) {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	if err := r.CRUD.Update(auth.Context(c), id, item); err != nil {
+	// The slug is kept unless a new one is explicitly requested, so that links stay stable.
+	if item.Slug == "" {
+		item.Slug = existing.Slug
+	}
+	if item.Slug != existing.Slug {
+		if err := r.assignSlug(ctx, &item, id); err != nil {
+			if errors.Is(err, errSlugRequired) {
+				return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+			}
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+
+	if err := hooks.PrepareSave(&item); err != nil {
+		if errors.Is(err, hooks.ErrPublishedAtRequired) {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
 
+	var editorID *string
+	if user := auth.GetAuthenticatedUser(c); user != nil {
+		editorID = &user.UserID
+	}
+	if err := r.Revisions.Save(ctx, existing, &item, editorID); err != nil {
+		if isUniqueViolation(err) {
+			return c.Status(409).JSON(fiber.Map{"error": "The slug was just taken, retry the request"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	r.Renderer.Invalidate(id)
+	r.Sitemap.Invalidate()
+
 	return response.SendFormatted(c, 200, item)
 }
 
+// Delete moves a post to the trash. Its comments and relations are kept until the post
+// is purged.
 func (r *PostResource) Delete(c *fiber.Ctx) error {
 	id := c.Params("id")
-	if err := r.CRUD.Delete(auth.Context(c), id); err != nil {
+	ctx := r.Policy.Context(c)
+
+	existing, err := r.CRUD.GetByID(ctx, id)
+	if err != nil {
+		ferr := lookupError(err)
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	if err := r.Policy.Authorize(c, existing.UserId); err != nil {
+		return c.Status(err.Code).JSON(fiber.Map{"error": err.Message})
+	}
+
+	if err := r.Trash.Trash(ctx, trash.TargetPost, id); err != nil {
+		if errors.Is(err, trash.ErrNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "Not found"})
+		}
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
+	r.Renderer.Invalidate(id)
+	r.Sitemap.Invalidate()
+
 	return c.SendStatus(204)
 }
 
-func GetPostBySlug(db database.Database, slug string) (*models.Post, error) {
-	query := fmt.Sprintf("SELECT * FROM post WHERE slug = %s", db.Dialect().Placeholder(1))
+// ListTrash lists the trashed posts of the authenticated user; admins see the whole trash.
+func (r *PostResource) ListTrash(c *fiber.Ctx) error {
+	limit := pagination.ParseIntQuery(c, "limit", r.PaginationLimit, r.PaginationMaxLimit)
+	page := pagination.ParseIntQuery(c, "page", 1, 10000)
+	if page < 1 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+	includeCount := c.Query("count", "true") != "false"
+
+	allowedFields := []string{"id", "user_id", "slug", "status", "title", "published_at", "updated_at", "created_at", "deleted_at"}
+
+	queryParams := make(url.Values)
+	c.Context().QueryArgs().VisitAll(func(key, value []byte) {
+		queryParams.Add(string(key), string(value))
+	})
+
+	filters := filter.NewFilterSet(allowedFields, r.DB.Dialect())
+	if err := filters.ParseFromQuery(queryParams); err != nil {
+		return pagination.SendPaginatedError(c, 400, err.Error())
+	}
+	whereClause, whereArgs := filters.BuildWhereClause()
+	whereClause, whereArgs, ok := trashedOnly(c, r.DB, r.Policy, whereClause, whereArgs)
+	if !ok {
+		return pagination.SendPaginatedError(c, 401, "Authentication required")
+	}
 
-	var post models.Post
-	err := db.QueryRow(context.Background(), query, slug).Scan(
-		&post.Id,
-		&post.UserId,
-		&post.Slug,
-		&post.Status,
-		&post.Title,
-		&post.Content,
-		&post.PublishedAt,
-		&post.UpdatedAt,
-		&post.CreatedAt,
-	)
+	ordering := filter.NewOrderSet(allowedFields)
+	if err := ordering.ParseFromQuery(queryParams); err != nil {
+		return pagination.SendPaginatedError(c, 400, err.Error())
+	}
+	orderByClause := ordering.BuildOrderByClause()
 
+	result, err := r.CRUD.GetAllPaginated(r.Policy.Context(c), crud.PaginationOptions{
+		Limit:         limit,
+		Offset:        offset,
+		IncludeCount:  includeCount,
+		WhereClause:   whereClause,
+		WhereArgs:     whereArgs,
+		OrderByClause: orderByClause,
+	})
+	if err != nil {
+		return pagination.SendPaginatedError(c, 500, err.Error())
+	}
+
+	return pagination.SendHydraCollection(c, result.Items, result.Total, limit, page, r.PaginationLimit)
+}
+
+// Restore takes a post out of the trash (owner or admin).
+func (r *PostResource) Restore(c *fiber.Ctx) error {
+	id := c.Params("id")
+	ctx := r.Policy.Context(c)
+
+	if ferr := authorizeTrashed(c, r.Trash, r.Policy, trash.TargetPost, id); ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	if err := r.Trash.Restore(ctx, trash.TargetPost, id); err != nil {
+		if errors.Is(err, trash.ErrNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "Not found"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	r.Sitemap.Invalidate()
+
+	restored, err := r.CRUD.GetByID(ctx, id)
+	if err != nil {
+		ferr := lookupError(err)
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+	return response.SendFormatted(c, 200, restored)
+}
+
+// Purge deletes a trashed post for good, along with its comments (owner or admin).
+func (r *PostResource) Purge(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	if ferr := authorizeTrashed(c, r.Trash, r.Policy, trash.TargetPost, id); ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	if err := r.Trash.Purge(r.Policy.Context(c), trash.TargetPost, id); err != nil {
+		if errors.Is(err, trash.ErrNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "Not found"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.SendStatus(204)
+}
+
+// GetMeta returns the SEO and Open Graph metadata of a post, as structured tags and as
+// HTML ready to be inserted in the page head.
+func (r *PostResource) GetMeta(c *fiber.Ctx) error {
+	ctx := r.Policy.Context(c)
+	post, err := r.CRUD.GetByID(ctx, c.Params("id"))
+	if err != nil {
+		ferr := lookupError(err)
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	page := seo.Page{
+		SiteTitle: r.SiteTitle,
+		URL:       r.Permalinks.URL(*post),
+	}
+
+	tags, err := r.Taxonomy.PostTags(ctx, post.Id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	for _, tag := range tags {
+		page.Tags = append(page.Tags, tag.Name)
+	}
+
+	if post.CoverImageId != nil {
+		cover, err := r.Media.Get(ctx, *post.CoverImageId)
+		if err != nil && !errors.Is(err, media.ErrNotFound) {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		if cover != nil {
+			page.ImageURL = r.Media.URL(cover)
+		}
+	}
+
+	return response.SendFormatted(c, 200, seo.ForPost(*post, page))
+}
+
+func (r *PostResource) GetTags(c *fiber.Ctx) error {
+	ctx := r.Policy.Context(c)
+	post, err := r.CRUD.GetByID(ctx, c.Params("id"))
+	if err != nil {
+		ferr := lookupError(err)
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	tags, err := r.Taxonomy.PostTags(ctx, post.Id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return response.SendFormatted(c, 200, tags)
+}
+
+// SetTags replaces the tags of a post. Unknown tags are created on the fly for editors
+// and admins, who curate the tag list, and rejected with a 400 for other authors.
+func (r *PostResource) SetTags(c *fiber.Ctx) error {
+	ctx := r.Policy.Context(c)
+	post, err := r.CRUD.GetByID(ctx, c.Params("id"))
+	if err != nil {
+		ferr := lookupError(err)
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	if err := r.Policy.Authorize(c, post.UserId); err != nil {
+		return c.Status(err.Code).JSON(fiber.Map{"error": err.Message})
+	}
+
+	var body struct {
+		Tags []string `json:"tags"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	tags, err := r.Taxonomy.SetPostTags(ctx, post.Id, body.Tags, r.Policy.IsEditor(c))
+	if errors.Is(err, taxonomy.ErrUnknownTag) {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return response.SendFormatted(c, 200, tags)
+}
+
+func (r *PostResource) GetCategories(c *fiber.Ctx) error {
+	ctx := r.Policy.Context(c)
+	post, err := r.CRUD.GetByID(ctx, c.Params("id"))
+	if err != nil {
+		ferr := lookupError(err)
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	categories, err := r.Taxonomy.PostCategories(ctx, post.Id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return response.SendFormatted(c, 200, categories)
+}
+
+// SetCategories replaces the categories of a post. Categories must already exist.
+func (r *PostResource) SetCategories(c *fiber.Ctx) error {
+	ctx := r.Policy.Context(c)
+	post, err := r.CRUD.GetByID(ctx, c.Params("id"))
+	if err != nil {
+		ferr := lookupError(err)
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	if err := r.Policy.Authorize(c, post.UserId); err != nil {
+		return c.Status(err.Code).JSON(fiber.Map{"error": err.Message})
+	}
+
+	var body struct {
+		Categories []string `json:"categories"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	categories, err := r.Taxonomy.SetPostCategories(ctx, post.Id, body.Categories)
+	if errors.Is(err, taxonomy.ErrUnknownCategory) {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return response.SendFormatted(c, 200, categories)
+}
+
+func (r *PostResource) render(post models.Post) RenderedPost {
+	return RenderedPost{
+		Post:        post,
+		ContentHTML: r.Renderer.Render(post.Id, post.Content).HTML,
+	}
+}
+
+// visibleOnly restricts a post where clause to the posts the caller may read, see
+// visibility.Scope.
+func (r *PostResource) visibleOnly(c *fiber.Ctx, clause string, args []any) (string, []any) {
+	cond, condArgs := r.Policy.Scope(c).Condition("")
+	if cond == "" {
+		return clause, args
+	}
+	return andWhere(r.DB, clause, args, cond, condArgs...)
+}
+
+// wantsHTML reports whether the client asked for rendered content with ?format=html.
+func wantsHTML(c *fiber.Ctx) bool {
+	return c.Query("format") == "html"
+}
+
+// keepUnsent gives an optional field the client did not send its existing value.
+func keepUnsent[T any](field **T, existing *T) {
+	if *field == nil {
+		*field = existing
+	}
+}
+
+// sentNull reports whether the JSON body sets field to null explicitly.
+func sentNull(body []byte, field string) bool {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return false
+	}
+	value, ok := fields[field]
+	return ok && string(value) == "null"
+}
+
+// checkCoverImage makes sure the cover image of item is a media uploaded by the post
+// author. Admins may use any media. An empty id removes the cover.
+func (r *PostResource) checkCoverImage(c *fiber.Ctx, item *models.Post) error {
+	if item.CoverImageId == nil {
+		return nil
+	}
+	if *item.CoverImageId == "" {
+		item.CoverImageId = nil
+		return nil
+	}
+
+	cover, err := r.Media.Get(r.Policy.Context(c), *item.CoverImageId)
+	if err != nil {
+		if errors.Is(err, media.ErrNotFound) {
+			return errUnknownCoverImage
+		}
+		return err
+	}
+	if r.Policy.IsAdmin(c) {
+		return nil
+	}
+	// Media of other users are reported as unknown, as reading them by id is.
+	if cover.UserId == nil || item.UserId == nil || *cover.UserId != *item.UserId {
+		return errUnknownCoverImage
+	}
+	return nil
+}
+
+// assignSlug normalizes the requested slug, falling back to the title, and suffixes it
+// until no other post uses it in the configured scope, which the post records so that the
+// matching unique index applies to it.
+func (r *PostResource) assignSlug(ctx context.Context, item *models.Post, excludeID string) error {
+	base := slug.Make(item.Slug)
+	if base == "" {
+		base = slug.Make(item.Title)
+	}
+	if base == "" {
+		return errSlugRequired
+	}
+
+	unique, err := r.Slugs.Unique(ctx, base, item.UserId, excludeID)
+	if err != nil {
+		return err
+	}
+	item.Slug = unique
+	item.SlugScope = string(r.Slugs.Scope())
+
+	return nil
+}
+
+func GetPostBySlug(db database.Database, postSlug string) (*models.Post, error) {
+	where, args := andWhere(db, "", nil, "slug = ?", postSlug)
+	where, args = notTrashed(db, where, args)
+
+	result, err := crud.New[models.Post](db).GetAllPaginated(context.Background(), crud.PaginationOptions{
+		Limit:       1,
+		WhereClause: where,
+		WhereArgs:   args,
+	})
 	if err != nil {
 		return nil, err
 	}
+	if len(result.Items) == 0 {
+		return nil, ErrPostNotFound
+	}
 
-	return &post, nil
+	return &result.Items[0], nil
 }
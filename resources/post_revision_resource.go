@@ -0,0 +1,183 @@
+package resources
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	auth "github.com/nicolasbonnici/gorest-auth"
+	"github.com/nicolasbonnici/gorest-blog/diff"
+	"github.com/nicolasbonnici/gorest-blog/hooks"
+	"github.com/nicolasbonnici/gorest-blog/models"
+	"github.com/nicolasbonnici/gorest-blog/policy"
+	"github.com/nicolasbonnici/gorest-blog/render"
+	"github.com/nicolasbonnici/gorest-blog/revisions"
+	"github.com/nicolasbonnici/gorest-blog/sitemap"
+	"github.com/nicolasbonnici/gorest/crud"
+	"github.com/nicolasbonnici/gorest/database"
+	"github.com/nicolasbonnici/gorest/response"
+)
+
+type PostRevisionResource struct {
+	DB        database.Database
+	Posts     *crud.CRUD[models.Post]
+	Revisions *revisions.Store
+	Policy    *policy.Policy
+	Renderer  *render.Renderer
+	Sitemap   *sitemap.Sitemap
+}
+
+type RevisionDiffResponse struct {
+	From int    `json:"from"`
+	To   int    `json:"to"`
+	Diff string `json:"diff"`
+}
+
+func RegisterPostRevisionRoutes(app *fiber.App, db database.Database, opts Options) {
+	res := &PostRevisionResource{
+		DB:        db,
+		Posts:     crud.NewWithHooks[models.Post](db, &hooks.PostHooks{}),
+		Revisions: revisions.NewStore(db),
+		Policy:    opts.Policy,
+		Renderer:  opts.Renderer,
+		Sitemap:   opts.Sitemap,
+	}
+
+	app.Get("/posts/:id/revisions", res.List)
+	app.Get("/posts/:id/revisions/diff", res.Diff)
+	app.Get("/posts/:id/revisions/:number", res.Get)
+	app.Post("/posts/:id/revisions/:number/restore", res.Restore)
+}
+
+func (r *PostRevisionResource) List(c *fiber.Ctx) error {
+	post, ferr := r.managedPost(c)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	items, err := r.Revisions.List(auth.Context(c), post.Id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return response.SendFormatted(c, 200, items)
+}
+
+func (r *PostRevisionResource) Get(c *fiber.Ctx) error {
+	post, ferr := r.managedPost(c)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	revision, ferr := r.revision(c, post.Id, c.Params("number"))
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	return response.SendFormatted(c, 200, revision)
+}
+
+// Diff returns the unified diff between the revisions given by the from and to query parameters.
+func (r *PostRevisionResource) Diff(c *fiber.Ctx) error {
+	post, ferr := r.managedPost(c)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	from, ferr := r.revision(c, post.Id, c.Query("from"))
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+	to, ferr := r.revision(c, post.Id, c.Query("to"))
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	return response.SendFormatted(c, 200, RevisionDiffResponse{
+		From: from.Number,
+		To:   to.Number,
+		Diff: diff.Unified(
+			fmt.Sprintf("revision %d", from.Number),
+			fmt.Sprintf("revision %d", to.Number),
+			revisionDocument(from),
+			revisionDocument(to),
+			diff.DefaultContext,
+		),
+	})
+}
+
+// Restore makes a revision the current title and content of the post. The restore
+// itself is recorded as a new revision.
+func (r *PostRevisionResource) Restore(c *fiber.Ctx) error {
+	post, ferr := r.managedPost(c)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	revision, ferr := r.revision(c, post.Id, c.Params("number"))
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	restored := *post
+	restored.Title = revision.Title
+	restored.Content = revision.Content
+
+	if err := hooks.PrepareSave(&restored); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var editorID *string
+	if user := auth.GetAuthenticatedUser(c); user != nil {
+		editorID = &user.UserID
+	}
+	ctx := r.Policy.Context(c)
+	if err := r.Revisions.Save(ctx, post, &restored, editorID); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	r.Renderer.Invalidate(post.Id)
+	r.Sitemap.Invalidate()
+
+	updated, err := r.Posts.GetByID(ctx, post.Id)
+	if err != nil {
+		return response.SendFormatted(c, 200, restored)
+	}
+
+	return response.SendFormatted(c, 200, updated)
+}
+
+// managedPost loads the post of the :id parameter. Revisions may expose unpublished
+// content, so they are restricted to the post owner and admins.
+func (r *PostRevisionResource) managedPost(c *fiber.Ctx) (*models.Post, *fiber.Error) {
+	post, err := r.Posts.GetByID(r.Policy.Context(c), c.Params("id"))
+	if err != nil {
+		return nil, lookupError(err)
+	}
+
+	if ferr := r.Policy.Authorize(c, post.UserId); ferr != nil {
+		return nil, ferr
+	}
+
+	return post, nil
+}
+
+func (r *PostRevisionResource) revision(c *fiber.Ctx, postID, number string) (*models.PostRevision, *fiber.Error) {
+	n, err := strconv.Atoi(number)
+	if err != nil || n < 1 {
+		return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Invalid revision number: %q", number))
+	}
+
+	revision, err := r.Revisions.Get(auth.Context(c), postID, n)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+	if revision == nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Revision not found")
+	}
+
+	return revision, nil
+}
+
+func revisionDocument(revision *models.PostRevision) string {
+	return "# " + revision.Title + "\n\n" + revision.Content + "\n"
+}
@@ -0,0 +1,78 @@
+package resources
+
+import (
+	"github.com/gofiber/fiber/v2"
+	auth "github.com/nicolasbonnici/gorest-auth"
+	"github.com/nicolasbonnici/gorest-blog/policy"
+	"github.com/nicolasbonnici/gorest-blog/search"
+	"github.com/nicolasbonnici/gorest/database"
+	"github.com/nicolasbonnici/gorest/pagination"
+	"github.com/nicolasbonnici/gorest/response"
+)
+
+type SearchResource struct {
+	DB                 database.Database
+	Search             *search.Service
+	PaginationLimit    int
+	PaginationMaxLimit int
+	Policy             *policy.Policy
+}
+
+type SearchResponse struct {
+	Query string          `json:"query"`
+	Items []search.Result `json:"items"`
+	Total int             `json:"total"`
+	Page  int             `json:"page"`
+	Limit int             `json:"limit"`
+}
+
+func RegisterSearchRoutes(app *fiber.App, db database.Database, opts Options) {
+	res := &SearchResource{
+		DB:                 db,
+		Search:             search.NewService(db),
+		PaginationLimit:    opts.PaginationLimit,
+		PaginationMaxLimit: opts.PaginationMaxLimit,
+		Policy:             opts.Policy,
+	}
+
+	app.Get("/search", res.List)
+}
+
+// List runs a ranked full-text search. Supported query parameters are q (required,
+// websearch syntax), type (post or comment), limit and page.
+func (r *SearchResource) List(c *fiber.Ctx) error {
+	text := c.Query("q")
+	if text == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "Query parameter q is required"})
+	}
+
+	kind := c.Query("type")
+	if kind != "" && kind != search.KindPost && kind != search.KindComment {
+		return c.Status(400).JSON(fiber.Map{"error": "Query parameter type must be post or comment"})
+	}
+
+	limit := pagination.ParseIntQuery(c, "limit", r.PaginationLimit, r.PaginationMaxLimit)
+	page := pagination.ParseIntQuery(c, "page", 1, 10000)
+	if page < 1 {
+		page = 1
+	}
+
+	results, total, err := r.Search.Search(auth.Context(c), search.Query{
+		Text:   text,
+		Kind:   kind,
+		Scope:  r.Policy.Scope(c),
+		Limit:  limit,
+		Offset: (page - 1) * limit,
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return response.SendFormatted(c, 200, SearchResponse{
+		Query: text,
+		Items: results,
+		Total: total,
+		Page:  page,
+		Limit: limit,
+	})
+}
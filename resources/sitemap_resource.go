@@ -0,0 +1,46 @@
+package resources
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nicolasbonnici/gorest-blog/sitemap"
+)
+
+type SitemapResource struct {
+	Sitemap *sitemap.Sitemap
+}
+
+func RegisterSitemapRoutes(app *fiber.App, opts Options) {
+	res := &SitemapResource{
+		Sitemap: opts.Sitemap,
+	}
+
+	app.Get("/sitemap.xml", res.Serve)
+	app.Get("/sitemap-:page.xml", res.Serve)
+}
+
+// Serve renders /sitemap.xml, or the sitemap of a sitemap index given by :page.
+func (r *SitemapResource) Serve(c *fiber.Ctx) error {
+	page := 0
+	if param := c.Params("page"); param != "" {
+		n, err := strconv.Atoi(param)
+		if err != nil || n < 1 {
+			return c.Status(404).JSON(fiber.Map{"error": "Not found"})
+		}
+		page = n
+	}
+
+	body, err := r.Sitemap.Page(c.Context(), page)
+	if errors.Is(err, sitemap.ErrPageNotFound) {
+		return c.Status(404).JSON(fiber.Map{"error": "Not found"})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	c.Set(fiber.HeaderContentType, "application/xml; charset=utf-8")
+	c.Set(fiber.HeaderCacheControl, "public, max-age=3600")
+	return c.Status(200).Send(body)
+}
@@ -0,0 +1,171 @@
+package resources
+
+import (
+	"net/url"
+
+	"github.com/gofiber/fiber/v2"
+	auth "github.com/nicolasbonnici/gorest-auth"
+	"github.com/nicolasbonnici/gorest-blog/models"
+	"github.com/nicolasbonnici/gorest-blog/policy"
+	"github.com/nicolasbonnici/gorest-blog/slug"
+	"github.com/nicolasbonnici/gorest/crud"
+	"github.com/nicolasbonnici/gorest/database"
+	"github.com/nicolasbonnici/gorest/filter"
+	"github.com/nicolasbonnici/gorest/pagination"
+	"github.com/nicolasbonnici/gorest/response"
+)
+
+type TagResource struct {
+	DB                 database.Database
+	CRUD               *crud.CRUD[models.Tag]
+	PaginationLimit    int
+	PaginationMaxLimit int
+	Policy             *policy.Policy
+}
+
+func RegisterTagRoutes(app *fiber.App, db database.Database, opts Options) {
+	res := &TagResource{
+		DB:                 db,
+		CRUD:               crud.New[models.Tag](db),
+		PaginationLimit:    opts.PaginationLimit,
+		PaginationMaxLimit: opts.PaginationMaxLimit,
+		Policy:             opts.Policy,
+	}
+
+	app.Get("/tags", res.List)
+	app.Get("/tags/:id", res.Get)
+	app.Post("/tags", res.Create)
+	app.Put("/tags/:id", res.Update)
+	app.Delete("/tags/:id", res.Delete)
+}
+
+func (r *TagResource) List(c *fiber.Ctx) error {
+	limit := pagination.ParseIntQuery(c, "limit", r.PaginationLimit, r.PaginationMaxLimit)
+	page := pagination.ParseIntQuery(c, "page", 1, 10000)
+	if page < 1 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+	includeCount := c.Query("count", "true") != "false"
+
+	allowedFields := []string{"id", "name", "slug", "updated_at", "created_at"}
+
+	queryParams := make(url.Values)
+	c.Context().QueryArgs().VisitAll(func(key, value []byte) {
+		queryParams.Add(string(key), string(value))
+	})
+
+	filters := filter.NewFilterSet(allowedFields, r.DB.Dialect())
+	if err := filters.ParseFromQuery(queryParams); err != nil {
+		return pagination.SendPaginatedError(c, 400, err.Error())
+	}
+	whereClause, whereArgs := filters.BuildWhereClause()
+
+	ordering := filter.NewOrderSet(allowedFields)
+	if err := ordering.ParseFromQuery(queryParams); err != nil {
+		return pagination.SendPaginatedError(c, 400, err.Error())
+	}
+	orderByClause := ordering.BuildOrderByClause()
+
+	result, err := r.CRUD.GetAllPaginated(auth.Context(c), crud.PaginationOptions{
+		Limit:         limit,
+		Offset:        offset,
+		IncludeCount:  includeCount,
+		WhereClause:   whereClause,
+		WhereArgs:     whereArgs,
+		OrderByClause: orderByClause,
+	})
+	if err != nil {
+		return pagination.SendPaginatedError(c, 500, err.Error())
+	}
+
+	return pagination.SendHydraCollection(c, result.Items, result.Total, limit, page, r.PaginationLimit)
+}
+
+func (r *TagResource) Get(c *fiber.Ctx) error {
+	id := c.Params("id")
+	item, err := r.CRUD.GetByID(auth.Context(c), id)
+	if err != nil {
+		ferr := lookupError(err)
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	return response.SendFormatted(c, 200, item)
+}
+
+// Create is restricted to editors and admins, who curate the shared tag list.
+func (r *TagResource) Create(c *fiber.Ctx) error {
+	if err := r.Policy.AuthorizeEditor(c); err != nil {
+		return c.Status(err.Code).JSON(fiber.Map{"error": err.Message})
+	}
+
+	var item models.Tag
+	if err := c.BodyParser(&item); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	item.Slug = slug.Make(item.Slug)
+	if item.Slug == "" {
+		item.Slug = slug.Make(item.Name)
+	}
+	if item.Slug == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "Tag name is required"})
+	}
+
+	ctx := auth.Context(c)
+	if err := r.CRUD.Create(ctx, item); err != nil {
+		if isUniqueViolation(err) {
+			return c.Status(409).JSON(fiber.Map{"error": "A tag with this slug already exists"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	created, err := r.CRUD.GetByID(ctx, item.Id)
+	if err != nil {
+		return response.SendFormatted(c, 201, item)
+	}
+
+	return response.SendFormatted(c, 201, created)
+}
+
+// Update and Delete are restricted to admins: tags are shared by every author.
+func (r *TagResource) Update(c *fiber.Ctx) error {
+	if err := r.Policy.Authorize(c, nil); err != nil {
+		return c.Status(err.Code).JSON(fiber.Map{"error": err.Message})
+	}
+
+	id := c.Params("id")
+	var item models.Tag
+	if err := c.BodyParser(&item); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	item.Slug = slug.Make(item.Slug)
+	if item.Slug == "" {
+		item.Slug = slug.Make(item.Name)
+	}
+	if item.Name == "" || item.Slug == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "Tag name is required"})
+	}
+
+	if err := r.CRUD.Update(auth.Context(c), id, item); err != nil {
+		if isUniqueViolation(err) {
+			return c.Status(409).JSON(fiber.Map{"error": "A tag with this slug already exists"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return response.SendFormatted(c, 200, item)
+}
+
+func (r *TagResource) Delete(c *fiber.Ctx) error {
+	if err := r.Policy.Authorize(c, nil); err != nil {
+		return c.Status(err.Code).JSON(fiber.Map{"error": err.Message})
+	}
+
+	id := c.Params("id")
+	if err := r.CRUD.Delete(auth.Context(c), id); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.SendStatus(204)
+}
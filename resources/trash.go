@@ -0,0 +1,44 @@
+package resources
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	auth "github.com/nicolasbonnici/gorest-auth"
+	"github.com/nicolasbonnici/gorest-blog/policy"
+	"github.com/nicolasbonnici/gorest-blog/trash"
+	"github.com/nicolasbonnici/gorest/database"
+)
+
+// notTrashed restricts a where clause to the rows that are not in the trash.
+func notTrashed(db database.Database, clause string, args []any) (string, []any) {
+	return andWhere(db, clause, args, "deleted_at IS NULL")
+}
+
+// trashedOnly restricts a where clause to the trash of the caller; admins see the whole
+// trash. It returns false when the caller is not authenticated.
+func trashedOnly(c *fiber.Ctx, db database.Database, pol *policy.Policy, clause string, args []any) (string, []any, bool) {
+	user := auth.GetAuthenticatedUser(c)
+	if user == nil {
+		return clause, args, false
+	}
+
+	clause, args = andWhere(db, clause, args, "deleted_at IS NOT NULL")
+	if !pol.IsAdmin(c) {
+		clause, args = andWhere(db, clause, args, "user_id = ?", user.UserID)
+	}
+	return clause, args, true
+}
+
+// authorizeTrashed checks that target id is in the trash and that the caller owns it or
+// is an admin.
+func authorizeTrashed(c *fiber.Ctx, store *trash.Store, pol *policy.Policy, target, id string) *fiber.Error {
+	owner, err := store.OwnerOf(auth.Context(c), target, id)
+	if errors.Is(err, trash.ErrNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, "Not found")
+	}
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+	return pol.Authorize(c, owner)
+}
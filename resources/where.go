@@ -0,0 +1,22 @@
+package resources
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nicolasbonnici/gorest/database"
+)
+
+// andWhere appends cond to a where clause built by filter.FilterSet.
+// cond uses "?" markers, rebound to dialect placeholders numbered after the existing args.
+func andWhere(db database.Database, clause string, args []any, cond string, condArgs ...any) (string, []any) {
+	for _, arg := range condArgs {
+		args = append(args, arg)
+		cond = strings.Replace(cond, "?", db.Dialect().Placeholder(len(args)), 1)
+	}
+
+	if strings.TrimSpace(clause) == "" {
+		return cond, args
+	}
+	return fmt.Sprintf("(%s) AND %s", clause, cond), args
+}
@@ -0,0 +1,201 @@
+package revisions
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nicolasbonnici/gorest-blog/models"
+	"github.com/nicolasbonnici/gorest/database"
+)
+
+// Store keeps the title/content history of posts.
+//
+// Revision 1 is the content a post had before its first recorded update, attributed to
+// the post owner; every later revision is the content saved by an update, attributed to
+// the user who made it.
+type Store struct {
+	db database.Database
+}
+
+func NewStore(db database.Database) *Store {
+	return &Store{db: db}
+}
+
+// Save writes after over the post before, on behalf of editorID, in one transaction with
+// what the update leaves behind: the former slug when it changed, and a revision when the
+// title or the content changed. A history entry that cannot be written rolls the update
+// back. Concurrent saves of a post are serialized by a lock on its row, so each revision
+// gets its own number. Posts in the trash are not updated.
+func (s *Store) Save(ctx context.Context, before, after *models.Post, editorID *string) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin post update: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// The numbering statement runs once the lock is granted, so it sees the revisions
+	// committed by the update it waited for.
+	if _, err := tx.Exec(ctx, "SELECT 1 FROM post WHERE id = $1 FOR UPDATE", before.Id); err != nil {
+		return fmt.Errorf("failed to lock post: %w", err)
+	}
+
+	update := `
+		UPDATE post SET
+			slug = $2, slug_scope = $3, status = $4, title = $5, content = $6,
+			excerpt = $7, excerpt_override = $8, word_count = $9, reading_time_minutes = $10,
+			cover_image_id = $11, canonical_url = $12, meta_title = $13, meta_description = $14,
+			og_title = $15, og_description = $16, og_image_url = $17, published_at = $18,
+			require_comment_approval = $19, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND deleted_at IS NULL`
+
+	if _, err := tx.Exec(ctx, update,
+		before.Id,
+		after.Slug,
+		after.SlugScope,
+		after.Status,
+		after.Title,
+		after.Content,
+		after.Excerpt,
+		after.ExcerptOverride,
+		after.WordCount,
+		after.ReadingTimeMinutes,
+		after.CoverImageId,
+		after.CanonicalUrl,
+		after.MetaTitle,
+		after.MetaDescription,
+		after.OgTitle,
+		after.OgDescription,
+		after.OgImageUrl,
+		after.PublishedAt,
+		after.RequireCommentApproval,
+	); err != nil {
+		return fmt.Errorf("failed to update post: %w", err)
+	}
+
+	if after.Slug != before.Slug {
+		// Same statement as slug.Registry.RecordChange, run in this transaction.
+		formerSlug := `
+			WITH cleared AS (
+				DELETE FROM post_slug_history WHERE post_id = $1 AND slug = $3
+			)
+			INSERT INTO post_slug_history (post_id, user_id, slug) VALUES ($1, $2, $3)`
+
+		if _, err := tx.Exec(ctx, formerSlug, before.Id, before.UserId, before.Slug); err != nil {
+			return fmt.Errorf("failed to record slug change: %w", err)
+		}
+	}
+
+	if before.Title != after.Title || before.Content != after.Content {
+		baselineAt := before.CreatedAt
+		if before.UpdatedAt != nil {
+			baselineAt = before.UpdatedAt
+		}
+		if baselineAt == nil {
+			now := time.Now()
+			baselineAt = &now
+		}
+
+		// Both inserts see the table as it was before the statement, hence the baseline
+		// (number 1) is only written when the post has no history yet and the new revision
+		// number defaults to 2 in that case.
+		revision := `
+			WITH baseline AS (
+				INSERT INTO post_revision (post_id, user_id, number, title, content, created_at)
+				SELECT $1, $2, 1, $3, $4, $5
+				WHERE NOT EXISTS (SELECT 1 FROM post_revision WHERE post_id = $1)
+			)
+			INSERT INTO post_revision (post_id, user_id, number, title, content)
+			SELECT $1, $6, COALESCE(MAX(number), 1) + 1, $7, $8
+			FROM post_revision
+			WHERE post_id = $1`
+
+		if _, err := tx.Exec(ctx, revision,
+			before.Id,
+			before.UserId,
+			before.Title,
+			before.Content,
+			baselineAt,
+			editorID,
+			after.Title,
+			after.Content,
+		); err != nil {
+			return fmt.Errorf("failed to record revision: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit post update: %w", err)
+	}
+	return nil
+}
+
+// List returns the revisions of a post, newest first.
+func (s *Store) List(ctx context.Context, postID string) ([]models.PostRevision, error) {
+	query := `
+		SELECT id, post_id, user_id, number, title, content, created_at
+		FROM post_revision
+		WHERE post_id = $1
+		ORDER BY number DESC`
+
+	rows, err := s.db.Query(ctx, query, postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query revisions: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	revisions := make([]models.PostRevision, 0)
+	for rows.Next() {
+		var revision models.PostRevision
+		if err := scanRevision(rows, &revision); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, nil
+}
+
+// Get returns a revision by number, or nil when the post has no such revision.
+func (s *Store) Get(ctx context.Context, postID string, number int) (*models.PostRevision, error) {
+	query := `
+		SELECT id, post_id, user_id, number, title, content, created_at
+		FROM post_revision
+		WHERE post_id = $1 AND number = $2`
+
+	rows, err := s.db.Query(ctx, query, postID, number)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query revision: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	if !rows.Next() {
+		return nil, nil
+	}
+
+	var revision models.PostRevision
+	if err := scanRevision(rows, &revision); err != nil {
+		return nil, err
+	}
+
+	return &revision, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRevision(rows scanner, revision *models.PostRevision) error {
+	if err := rows.Scan(
+		&revision.Id,
+		&revision.PostId,
+		&revision.UserId,
+		&revision.Number,
+		&revision.Title,
+		&revision.Content,
+		&revision.CreatedAt,
+	); err != nil {
+		return fmt.Errorf("failed to scan revision: %w", err)
+	}
+	return nil
+}
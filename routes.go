@@ -2,12 +2,61 @@ package blog
 
 import (
 	"github.com/gofiber/fiber/v2"
+	"github.com/nicolasbonnici/gorest-blog/media"
+	"github.com/nicolasbonnici/gorest-blog/permalink"
+	"github.com/nicolasbonnici/gorest-blog/policy"
+	"github.com/nicolasbonnici/gorest-blog/render"
 	"github.com/nicolasbonnici/gorest-blog/resources"
+	"github.com/nicolasbonnici/gorest-blog/sitemap"
 	"github.com/nicolasbonnici/gorest/database"
 )
 
-func RegisterBlogRoutes(app *fiber.App, db database.Database, paginationLimit, maxPaginationLimit int) {
-	resources.RegisterPostRoutes(app, db, paginationLimit, maxPaginationLimit)
-	resources.RegisterCommentRoutes(app, db, paginationLimit, maxPaginationLimit)
-	resources.RegisterLikeRoutes(app, db, paginationLimit, maxPaginationLimit)
+func RegisterBlogRoutes(app *fiber.App, db database.Database, config Config) {
+	registerBlogRoutes(app, db, newOptions(db, config))
+}
+
+// newOptions builds the resource options described by config. The caches and stores it
+// creates are shared by every resource, and by the background jobs of the plugin.
+func newOptions(db database.Database, config Config) resources.Options {
+	links := permalink.New(config.SiteURL, config.PostPathTemplate)
+
+	return resources.Options{
+		PaginationLimit:        config.PaginationLimit,
+		PaginationMaxLimit:     config.MaxPaginationLimit,
+		Policy:                 policy.New(config.AdminRole, config.EditorRole, config.RoleResolver),
+		FeedItemCount:          config.FeedItemCount,
+		SiteURL:                config.SiteURL,
+		SiteTitle:              config.SiteTitle,
+		SiteDescription:        config.SiteDescription,
+		SlugScope:              config.SlugScope,
+		RequireCommentApproval: config.RequireCommentApproval,
+		ReactionKinds:          config.ReactionKinds,
+		Renderer:               render.New(config.RenderCacheSize),
+		Media:                  newMediaStore(db, config),
+		Permalinks:             links,
+		Sitemap:                sitemap.New(db, links, config.SiteURL),
+	}
+}
+
+func registerBlogRoutes(app *fiber.App, db database.Database, opts resources.Options) {
+	resources.RegisterPostRoutes(app, db, opts)
+	resources.RegisterPostRevisionRoutes(app, db, opts)
+	resources.RegisterCommentRoutes(app, db, opts)
+	resources.RegisterLikeRoutes(app, db, opts)
+	resources.RegisterTagRoutes(app, db, opts)
+	resources.RegisterCategoryRoutes(app, db, opts)
+	resources.RegisterSearchRoutes(app, db, opts)
+	resources.RegisterFeedRoutes(app, db, opts)
+	resources.RegisterMediaRoutes(app, db, opts)
+	resources.RegisterSitemapRoutes(app, opts)
+}
+
+// newMediaStore returns the media store described by config, storing files under
+// MediaDir unless a custom MediaStorage is configured.
+func newMediaStore(db database.Database, config Config) *media.Store {
+	storage := config.MediaStorage
+	if storage == nil {
+		storage = media.NewLocalStorage(config.MediaDir)
+	}
+	return media.NewStore(db, storage, config.MediaMaxSize, config.SiteURL)
 }
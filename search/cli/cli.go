@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/nicolasbonnici/gorest-blog/search"
+	"github.com/nicolasbonnici/gorest/database"
+	_ "github.com/nicolasbonnici/gorest/database/postgres"
+)
+
+// Run stores the search language and rebuilds the search vectors, and returns an exit code
+// This is the main entry point for the search reindex CLI
+func Run(args []string) int {
+	fs := flag.NewFlagSet("reindex-search", flag.ExitOnError)
+	language := fs.String("language", search.DefaultLanguage, "PostgreSQL text search configuration (e.g. english, french)")
+	force := fs.Bool("force", false, "Rebuild the search vectors even when the language is unchanged")
+	timeout := fs.Duration("timeout", 30*time.Minute, "Maximum duration of the reindex")
+
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing flags: %v\n", err)
+		return 1
+	}
+
+	// Get database URL from environment
+	databaseURL := os.Getenv("DATABASE_URL")
+	if databaseURL == "" {
+		fmt.Fprintln(os.Stderr, "Error: DATABASE_URL environment variable is required")
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	db, err := database.Open("postgres", databaseURL)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to connect to database: %v\n", err)
+		return 1
+	}
+	defer func() { _ = db.Close() }()
+
+	reindexed, err := search.SetLanguage(ctx, db, *language, *force)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Reindex failed: %v\n", err)
+		return 1
+	}
+
+	if !reindexed && !*force {
+		fmt.Printf("Search language already %s, nothing to reindex\n", *language)
+		return 0
+	}
+	fmt.Printf("Search index rebuilt with the %s configuration\n", *language)
+	return 0
+}
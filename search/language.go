@@ -0,0 +1,70 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/nicolasbonnici/gorest/database"
+)
+
+// ErrUnknownLanguage is returned by SetLanguage for a name that is not an installed text
+// search configuration.
+var ErrUnknownLanguage = errors.New("unknown text search configuration")
+
+// Language returns the text search configuration the search vectors are built with.
+func Language(ctx context.Context, db database.Database) (string, error) {
+	var language string
+	if err := db.QueryRow(ctx, "SELECT blog_search_language()::text").Scan(&language); err != nil {
+		return "", fmt.Errorf("failed to read search language: %w", err)
+	}
+	return language, nil
+}
+
+// SetLanguage stores language as the text search configuration and rebuilds the search
+// vectors of every post and comment when it changed, or when force is set. The setting and
+// the vectors are written in one transaction, so they never disagree. language must name
+// an installed configuration, such as english or simple, or ErrUnknownLanguage is returned.
+// It rewrites whole tables, so it runs from the reindex command rather than from requests.
+func SetLanguage(ctx context.Context, db database.Database, language string, force bool) (bool, error) {
+	var known, changed bool
+	query := `
+		SELECT EXISTS (SELECT 1 FROM pg_ts_config WHERE cfgname = $1),
+		       blog_search_language()::text <> $1`
+	if err := db.QueryRow(ctx, query, language).Scan(&known, &changed); err != nil {
+		return false, fmt.Errorf("failed to read search language: %w", err)
+	}
+	if !known {
+		return false, fmt.Errorf("%w: %s", ErrUnknownLanguage, language)
+	}
+	if !changed && !force {
+		return false, nil
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin reindex: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	store := `
+		UPDATE blog_setting SET value = $1::regconfig::text, updated_at = CURRENT_TIMESTAMP
+		WHERE key = 'search_language'`
+	if _, err := tx.Exec(ctx, store, language); err != nil {
+		return false, fmt.Errorf("failed to store search language: %w", err)
+	}
+
+	for _, reindex := range []string{
+		"UPDATE post SET search_vector = blog_search_document(title, content)",
+		"UPDATE comment SET search_vector = blog_search_document(NULL, content)",
+	} {
+		if _, err := tx.Exec(ctx, reindex); err != nil {
+			return false, fmt.Errorf("failed to rebuild search index: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit reindex: %w", err)
+	}
+	return changed, nil
+}
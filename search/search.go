@@ -0,0 +1,146 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/nicolasbonnici/gorest-blog/visibility"
+	"github.com/nicolasbonnici/gorest/database"
+)
+
+const DefaultLanguage = "english"
+
+const (
+	KindPost    = "post"
+	KindComment = "comment"
+)
+
+// Highlight markers are control characters so that the snippet can be HTML-escaped
+// before they are swapped for <mark> tags.
+const (
+	startMarker = "\x02"
+	stopMarker  = "\x03"
+)
+
+var headlineOptions = fmt.Sprintf("StartSel=%s, StopSel=%s, MaxFragments=2, MaxWords=30, MinWords=10", startMarker, stopMarker)
+
+// Query describes a search request.
+type Query struct {
+	Text string
+	// Kind restricts results to posts or comments; empty searches both.
+	Kind string
+	// Scope restricts results to the visible posts and the comments attached to them.
+	Scope  visibility.Scope
+	Limit  int
+	Offset int
+}
+
+// Result is a ranked search hit. Snippet is HTML-escaped, with matches wrapped in <mark>.
+type Result struct {
+	Kind      string     `json:"kind"`
+	Id        string     `json:"id"`
+	PostId    string     `json:"postId"`
+	PostTitle string     `json:"postTitle"`
+	PostSlug  string     `json:"postSlug"`
+	Snippet   string     `json:"snippet"`
+	Rank      float64    `json:"rank"`
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+}
+
+// Service runs ranked full-text searches against the tsvector columns maintained by
+// the search migration, in the text search configuration stored in blog_setting.
+type Service struct {
+	db database.Database
+}
+
+func NewService(db database.Database) *Service {
+	return &Service{
+		db: db,
+	}
+}
+
+// Search returns the results of the page described by q, best matches first, and the
+// total number of matches.
+func (s *Service) Search(ctx context.Context, q Query) ([]Result, int, error) {
+	// $1 search text, $2 ts_headline options
+	args := []any{q.Text, headlineOptions}
+
+	scope := ""
+	if cond, condArgs := q.Scope.Condition("p"); cond != "" {
+		for _, arg := range condArgs {
+			args = append(args, arg)
+			cond = strings.Replace(cond, "?", fmt.Sprintf("$%d", len(args)), 1)
+		}
+		scope = " AND " + cond
+	}
+
+	parts := make([]string, 0, 2)
+	if q.Kind == "" || q.Kind == KindPost {
+		parts = append(parts, postQuery+scope)
+	}
+	if q.Kind == "" || q.Kind == KindComment {
+		parts = append(parts, commentQuery+scope)
+	}
+	if len(parts) == 0 {
+		return nil, 0, fmt.Errorf("unknown search kind: %s", q.Kind)
+	}
+
+	hits := strings.Join(parts, " UNION ALL ")
+
+	var total int
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM (%s) hits", hits)
+	if err := s.db.QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count search results: %w", err)
+	}
+
+	pageQuery := fmt.Sprintf(`
+		SELECT kind, id, post_id, post_title, post_slug, snippet, rank, created_at
+		FROM (%s) hits
+		ORDER BY rank DESC, created_at DESC
+		LIMIT $%d OFFSET $%d`, hits, len(args)+1, len(args)+2)
+
+	rows, err := s.db.Query(ctx, pageQuery, append(args, q.Limit, q.Offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("search failed: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	results := make([]Result, 0)
+	for rows.Next() {
+		var r Result
+		if err := rows.Scan(&r.Kind, &r.Id, &r.PostId, &r.PostTitle, &r.PostSlug, &r.Snippet, &r.Rank, &r.CreatedAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan search result: %w", err)
+		}
+		r.Snippet = highlight(r.Snippet)
+		results = append(results, r)
+	}
+
+	return results, total, nil
+}
+
+const postQuery = `
+		SELECT 'post' AS kind, p.id, p.id AS post_id, p.title AS post_title, p.slug AS post_slug,
+		       ts_headline(blog_search_language(), p.content, websearch_to_tsquery(blog_search_language(), $1), $2) AS snippet,
+		       ts_rank_cd(p.search_vector, websearch_to_tsquery(blog_search_language(), $1)) AS rank,
+		       p.created_at
+		FROM post p
+		WHERE p.search_vector @@ websearch_to_tsquery(blog_search_language(), $1) AND p.deleted_at IS NULL`
+
+const commentQuery = `
+		SELECT 'comment' AS kind, c.id, c.post_id, p.title AS post_title, p.slug AS post_slug,
+		       ts_headline(blog_search_language(), c.content, websearch_to_tsquery(blog_search_language(), $1), $2) AS snippet,
+		       ts_rank_cd(c.search_vector, websearch_to_tsquery(blog_search_language(), $1)) AS rank,
+		       c.created_at
+		FROM comment c
+		JOIN post p ON p.id = c.post_id
+		WHERE c.search_vector @@ websearch_to_tsquery(blog_search_language(), $1) AND c.status = 'approved'
+		  AND c.deleted_at IS NULL AND p.deleted_at IS NULL`
+
+func highlight(snippet string) string {
+	snippet = html.EscapeString(snippet)
+	snippet = strings.ReplaceAll(snippet, startMarker, "<mark>")
+	return strings.ReplaceAll(snippet, stopMarker, "</mark>")
+}
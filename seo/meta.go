@@ -0,0 +1,144 @@
+package seo
+
+import (
+	"errors"
+	"html"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/nicolasbonnici/gorest-blog/models"
+	"github.com/nicolasbonnici/gorest-blog/render"
+)
+
+// DescriptionLength is the maximum length of a generated description, in characters.
+const DescriptionLength = 160
+
+var ErrInvalidURL = errors.New("canonicalUrl and ogImageUrl must be absolute http(s) URLs")
+
+// Tag is a <meta> tag of a page head: either a named tag (description, twitter:*) or an
+// Open Graph property.
+type Tag struct {
+	Name     string `json:"name,omitempty"`
+	Property string `json:"property,omitempty"`
+	Content  string `json:"content"`
+}
+
+// Meta holds the head metadata of a post page. HTML is the same metadata rendered as
+// escaped <title>, <link> and <meta> tags.
+type Meta struct {
+	Title        string `json:"title"`
+	Description  string `json:"description"`
+	CanonicalURL string `json:"canonicalUrl"`
+	Tags         []Tag  `json:"tags"`
+	HTML         string `json:"html"`
+}
+
+// Page describes what the metadata of a post page depends on besides the post itself.
+type Page struct {
+	SiteTitle string
+	// URL is the blog URL of the post, the canonical URL unless the post sets one.
+	URL string
+	// ImageURL is the URL of the post cover image, if any.
+	ImageURL string
+	Tags     []string
+}
+
+// Normalize trims the SEO fields of post, turns empty ones into nil so that defaults
+// apply, and checks its URLs.
+func Normalize(post *models.Post) error {
+	for _, field := range []**string{&post.CanonicalUrl, &post.MetaTitle, &post.MetaDescription, &post.OgTitle, &post.OgDescription, &post.OgImageUrl} {
+		if *field == nil {
+			continue
+		}
+		value := strings.TrimSpace(**field)
+		if value == "" {
+			*field = nil
+		} else {
+			*field = &value
+		}
+	}
+
+	for _, field := range []*string{post.CanonicalUrl, post.OgImageUrl} {
+		if field != nil && !isAbsoluteURL(*field) {
+			return ErrInvalidURL
+		}
+	}
+	return nil
+}
+
+// ForPost returns the head metadata of post. Fields left empty on the post fall back to
+// their generic counterpart: the title, the excerpt, the post URL and the cover image.
+func ForPost(post models.Post, page Page) Meta {
+	title := firstOf(post.MetaTitle, post.Title)
+	description := firstOf(post.MetaDescription, render.Excerpt(post.Excerpt, DescriptionLength))
+	canonical := firstOf(post.CanonicalUrl, page.URL)
+	image := firstOf(post.OgImageUrl, page.ImageURL)
+
+	tags := []Tag{
+		{Name: "description", Content: description},
+		{Property: "og:type", Content: "article"},
+		{Property: "og:title", Content: firstOf(post.OgTitle, title)},
+		{Property: "og:description", Content: firstOf(post.OgDescription, description)},
+		{Property: "og:url", Content: canonical},
+	}
+	if page.SiteTitle != "" {
+		tags = append(tags, Tag{Property: "og:site_name", Content: page.SiteTitle})
+	}
+	if image != "" {
+		tags = append(tags, Tag{Property: "og:image", Content: image})
+	}
+	if post.PublishedAt != nil {
+		tags = append(tags, Tag{Property: "article:published_time", Content: post.PublishedAt.UTC().Format(time.RFC3339)})
+	}
+	if post.UpdatedAt != nil {
+		tags = append(tags, Tag{Property: "article:modified_time", Content: post.UpdatedAt.UTC().Format(time.RFC3339)})
+	}
+	for _, tag := range page.Tags {
+		tags = append(tags, Tag{Property: "article:tag", Content: tag})
+	}
+
+	card := "summary"
+	if image != "" {
+		card = "summary_large_image"
+	}
+	tags = append(tags, Tag{Name: "twitter:card", Content: card})
+
+	meta := Meta{
+		Title:        title,
+		Description:  description,
+		CanonicalURL: canonical,
+		Tags:         tags,
+	}
+	meta.HTML = meta.render()
+	return meta
+}
+
+func (m Meta) render() string {
+	var b strings.Builder
+	b.WriteString("<title>" + html.EscapeString(m.Title) + "</title>\n")
+	if m.CanonicalURL != "" {
+		b.WriteString(`<link rel="canonical" href="` + html.EscapeString(m.CanonicalURL) + "\">\n")
+	}
+	for _, tag := range m.Tags {
+		if tag.Property != "" {
+			b.WriteString(`<meta property="` + html.EscapeString(tag.Property) + `"`)
+		} else {
+			b.WriteString(`<meta name="` + html.EscapeString(tag.Name) + `"`)
+		}
+		b.WriteString(` content="` + html.EscapeString(tag.Content) + "\">\n")
+	}
+	return b.String()
+}
+
+func firstOf(value *string, fallback string) string {
+	if value != nil && *value != "" {
+		return *value
+	}
+	return fallback
+}
+
+func isAbsoluteURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -0,0 +1,102 @@
+package seo
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nicolasbonnici/gorest-blog/models"
+)
+
+func ptr(s string) *string {
+	return &s
+}
+
+func TestNormalize(t *testing.T) {
+	post := models.Post{
+		CanonicalUrl: ptr("  https://example.com/original  "),
+		MetaTitle:    ptr("   "),
+		OgTitle:      ptr(" Shared title "),
+	}
+	if err := Normalize(&post); err != nil {
+		t.Fatalf("Normalize: %v", err)
+	}
+	if *post.CanonicalUrl != "https://example.com/original" || post.MetaTitle != nil || *post.OgTitle != "Shared title" {
+		t.Errorf("got canonical %q, meta title %v, og title %q", *post.CanonicalUrl, post.MetaTitle, *post.OgTitle)
+	}
+
+	for _, url := range []string{"/relative", "ftp://example.com/a", "javascript:alert(1)"} {
+		if err := Normalize(&models.Post{OgImageUrl: ptr(url)}); !errors.Is(err, ErrInvalidURL) {
+			t.Errorf("Normalize(%q): got %v, want ErrInvalidURL", url, err)
+		}
+	}
+}
+
+func TestForPostDefaults(t *testing.T) {
+	published := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	meta := ForPost(models.Post{
+		Title:       "Hello <Go>",
+		Excerpt:     "A first post",
+		PublishedAt: &published,
+	}, Page{
+		SiteTitle: "My Blog",
+		URL:       "https://blog.example.com/posts/hello",
+		Tags:      []string{"go"},
+	})
+
+	if meta.Title != "Hello <Go>" || meta.Description != "A first post" || meta.CanonicalURL != "https://blog.example.com/posts/hello" {
+		t.Errorf("got %+v", meta)
+	}
+
+	want := map[string]string{
+		"description":            "A first post",
+		"og:title":               "Hello <Go>",
+		"og:url":                 "https://blog.example.com/posts/hello",
+		"og:site_name":           "My Blog",
+		"article:published_time": "2024-01-15T10:00:00Z",
+		"article:tag":            "go",
+		"twitter:card":           "summary",
+	}
+	got := make(map[string]string)
+	for _, tag := range meta.Tags {
+		got[tag.Name+tag.Property] = tag.Content
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("tag %s: got %q, want %q", key, got[key], value)
+		}
+	}
+	if _, ok := got["og:image"]; ok {
+		t.Error("og:image set without image")
+	}
+
+	if !strings.Contains(meta.HTML, "<title>Hello &lt;Go&gt;</title>") {
+		t.Errorf("title is not escaped: %s", meta.HTML)
+	}
+}
+
+func TestForPostOverrides(t *testing.T) {
+	meta := ForPost(models.Post{
+		Title:           "Title",
+		MetaTitle:       ptr("Meta title"),
+		MetaDescription: ptr(`Say "hi"`),
+		CanonicalUrl:    ptr("https://example.com/original"),
+		OgImageUrl:      ptr("https://example.com/og.png"),
+	}, Page{URL: "https://blog.example.com/posts/title", ImageURL: "https://blog.example.com/cover.png"})
+
+	if meta.Title != "Meta title" || meta.CanonicalURL != "https://example.com/original" {
+		t.Errorf("got title %q, canonical %q", meta.Title, meta.CanonicalURL)
+	}
+
+	got := make(map[string]string)
+	for _, tag := range meta.Tags {
+		got[tag.Name+tag.Property] = tag.Content
+	}
+	if got["og:image"] != "https://example.com/og.png" || got["twitter:card"] != "summary_large_image" {
+		t.Errorf("got og:image %q, twitter:card %q", got["og:image"], got["twitter:card"])
+	}
+	if !strings.Contains(meta.HTML, `content="Say &#34;hi&#34;"`) {
+		t.Errorf("description is not escaped: %s", meta.HTML)
+	}
+}
@@ -0,0 +1,212 @@
+package sitemap
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nicolasbonnici/gorest-blog/models"
+	"github.com/nicolasbonnici/gorest-blog/permalink"
+	"github.com/nicolasbonnici/gorest-blog/types"
+	"github.com/nicolasbonnici/gorest/database"
+)
+
+const (
+	// MaxURLs is the number of URLs allowed in a single sitemap by the sitemaps protocol.
+	// Larger blogs get a sitemap index pointing at sitemaps of at most MaxURLs posts.
+	MaxURLs = 50000
+	// DefaultMaxAge bounds how long a generated document is served from cache, which
+	// covers changes made by other processes (e.g. the import CLI).
+	DefaultMaxAge = time.Hour
+
+	namespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+)
+
+var ErrPageNotFound = errors.New("sitemap page not found")
+
+type urlSet struct {
+	XMLName xml.Name   `xml:"urlset"`
+	Xmlns   string     `xml:"xmlns,attr"`
+	URLs    []urlEntry `xml:"url"`
+}
+
+type urlEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name     `xml:"sitemapindex"`
+	Xmlns    string       `xml:"xmlns,attr"`
+	Sitemaps []indexEntry `xml:"sitemap"`
+}
+
+type indexEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// Sitemap generates the sitemap documents of the published posts and caches them until
+// Invalidate is called or they are older than DefaultMaxAge.
+//
+// Page 0 is the root document served at /sitemap.xml: the only sitemap when the blog
+// has at most MaxURLs published posts, a sitemap index otherwise. Pages 1..n are the
+// sitemaps listed by the index, served at /sitemap-<n>.xml.
+type Sitemap struct {
+	db      database.Database
+	links   *permalink.Builder
+	baseURL string
+	maxAge  time.Duration
+
+	mu          sync.Mutex
+	pages       map[int][]byte
+	generatedAt time.Time
+}
+
+// New returns a sitemap listing post pages built by links. baseURL is the public URL of
+// the site, used to link the sitemaps of an index.
+func New(db database.Database, links *permalink.Builder, baseURL string) *Sitemap {
+	return &Sitemap{
+		db:      db,
+		links:   links,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		maxAge:  DefaultMaxAge,
+		pages:   make(map[int][]byte),
+	}
+}
+
+// Invalidate drops the cached documents. It is called whenever a post is published,
+// updated or deleted.
+func (s *Sitemap) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pages = make(map[int][]byte)
+}
+
+// Page returns the XML document of page, generating it if needed.
+func (s *Sitemap) Page(ctx context.Context, page int) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if time.Since(s.generatedAt) > s.maxAge {
+		s.pages = make(map[int][]byte)
+		s.generatedAt = time.Now()
+	}
+	if body, ok := s.pages[page]; ok {
+		return body, nil
+	}
+
+	body, err := s.generate(ctx, page)
+	if err != nil {
+		return nil, err
+	}
+	s.pages[page] = body
+	return body, nil
+}
+
+// PageURL returns the URL of the sitemap page n of the index.
+func (s *Sitemap) PageURL(n int) string {
+	return fmt.Sprintf("%s/sitemap-%d.xml", s.baseURL, n)
+}
+
+func (s *Sitemap) generate(ctx context.Context, page int) ([]byte, error) {
+	chunks, err := s.chunks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case page == 0 && len(chunks) <= 1:
+		return s.urlSet(ctx, 1)
+	case page == 0:
+		index := sitemapIndex{Xmlns: namespace, Sitemaps: make([]indexEntry, 0, len(chunks))}
+		for i, lastMod := range chunks {
+			index.Sitemaps = append(index.Sitemaps, indexEntry{Loc: s.PageURL(i + 1), LastMod: formatDate(lastMod)})
+		}
+		return marshal(index)
+	case page >= 1 && page <= len(chunks) && len(chunks) > 1:
+		return s.urlSet(ctx, page)
+	default:
+		return nil, ErrPageNotFound
+	}
+}
+
+// chunks returns, for every group of MaxURLs published posts, the date of the most recent
+// change in the group.
+func (s *Sitemap) chunks(ctx context.Context) ([]time.Time, error) {
+	query := `
+		SELECT (n - 1) / $2 AS chunk, MAX(lastmod)
+		FROM (
+			SELECT ROW_NUMBER() OVER (ORDER BY published_at, id) AS n,
+			       COALESCE(updated_at, published_at, created_at) AS lastmod
+			FROM post
+			WHERE status = $1 AND deleted_at IS NULL
+		) numbered
+		GROUP BY chunk
+		ORDER BY chunk`
+
+	rows, err := s.db.Query(ctx, query, types.PostStatusPublished.String(), MaxURLs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count sitemap posts: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	chunks := make([]time.Time, 0)
+	for rows.Next() {
+		var chunk int
+		var lastMod time.Time
+		if err := rows.Scan(&chunk, &lastMod); err != nil {
+			return nil, fmt.Errorf("failed to scan sitemap chunk: %w", err)
+		}
+		chunks = append(chunks, lastMod)
+	}
+	return chunks, nil
+}
+
+// urlSet renders the sitemap of the published posts of page, in publication order.
+func (s *Sitemap) urlSet(ctx context.Context, page int) ([]byte, error) {
+	query := `
+		SELECT id, slug, published_at, created_at, COALESCE(updated_at, published_at, created_at)
+		FROM post
+		WHERE status = $1 AND deleted_at IS NULL
+		ORDER BY published_at, id
+		LIMIT $2 OFFSET $3`
+
+	rows, err := s.db.Query(ctx, query, types.PostStatusPublished.String(), MaxURLs, (page-1)*MaxURLs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sitemap posts: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	set := urlSet{Xmlns: namespace, URLs: make([]urlEntry, 0)}
+	for rows.Next() {
+		var post models.Post
+		var lastMod time.Time
+		if err := rows.Scan(&post.Id, &post.Slug, &post.PublishedAt, &post.CreatedAt, &lastMod); err != nil {
+			return nil, fmt.Errorf("failed to scan sitemap post: %w", err)
+		}
+		set.URLs = append(set.URLs, urlEntry{Loc: s.links.URL(post), LastMod: formatDate(lastMod)})
+	}
+
+	return marshal(set)
+}
+
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func marshal(v any) ([]byte, error) {
+	body, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
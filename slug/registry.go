@@ -0,0 +1,142 @@
+package slug
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nicolasbonnici/gorest-blog/visibility"
+	"github.com/nicolasbonnici/gorest/database"
+)
+
+// Scope defines among which posts a slug must be unique.
+type Scope string
+
+const (
+	ScopeGlobal Scope = "global"
+	ScopeAuthor Scope = "author"
+)
+
+func (s Scope) IsValid() bool {
+	return s == ScopeGlobal || s == ScopeAuthor
+}
+
+// Registry allocates unique post slugs and remembers the slugs a post used to have,
+// so that old URLs can be redirected. Unique picks a free slug; the database enforces it
+// with uniq_post_user_slug, and with uniq_post_slug for posts whose slug_scope is global,
+// so concurrent writers racing for a slug get a unique violation.
+type Registry struct {
+	db    database.Database
+	scope Scope
+}
+
+func NewRegistry(db database.Database, scope Scope) *Registry {
+	if !scope.IsValid() {
+		scope = ScopeGlobal
+	}
+	return &Registry{
+		db:    db,
+		scope: scope,
+	}
+}
+
+func (r *Registry) Scope() Scope {
+	return r.scope
+}
+
+// Unique returns base when no other post uses it in the configured scope, or base
+// suffixed with the smallest free "-N" (N >= 2) otherwise. excludeID is the id of the
+// post being updated, if any.
+func (r *Registry) Unique(ctx context.Context, base string, userID *string, excludeID string) (string, error) {
+	query := "SELECT slug FROM post WHERE (slug = $1 OR slug LIKE $2) AND id::text <> $3"
+	args := []any{base, base + "-%", excludeID}
+	if r.scope == ScopeAuthor {
+		query += " AND user_id IS NOT DISTINCT FROM $4"
+		args = append(args, userID)
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return "", fmt.Errorf("failed to check slug availability: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	taken := make(map[string]bool)
+	for rows.Next() {
+		var existing string
+		if err := rows.Scan(&existing); err != nil {
+			return "", fmt.Errorf("failed to scan slug: %w", err)
+		}
+		taken[existing] = true
+	}
+
+	return firstFree(base, taken), nil
+}
+
+// firstFree returns base when it is not taken, or base suffixed with the smallest "-N"
+// (N >= 2) that is not.
+func firstFree(base string, taken map[string]bool) string {
+	if !taken[base] {
+		return base
+	}
+	for n := 2; ; n++ {
+		candidate := base + "-" + strconv.Itoa(n)
+		if !taken[candidate] {
+			return candidate
+		}
+	}
+}
+
+// RecordChange remembers that a post was previously reachable under oldSlug. Post updates
+// record it in their own transaction through revisions.Store.Save instead.
+func (r *Registry) RecordChange(ctx context.Context, postID string, userID *string, oldSlug string) error {
+	query := `
+		WITH cleared AS (
+			DELETE FROM post_slug_history WHERE post_id = $1 AND slug = $3
+		)
+		INSERT INTO post_slug_history (post_id, user_id, slug) VALUES ($1, $2, $3)`
+
+	if _, err := r.db.Exec(ctx, query, postID, userID, oldSlug); err != nil {
+		return fmt.Errorf("failed to record slug change: %w", err)
+	}
+	return nil
+}
+
+// Resolve looks up a former slug and returns the current slug of the post that used it.
+// authorID restricts the lookup to one author when not empty. Posts in the trash or not
+// visible in scope are ignored. found is false when no such post used the slug.
+func (r *Registry) Resolve(ctx context.Context, scope visibility.Scope, oldSlug, authorID string) (current string, found bool, err error) {
+	args := []any{oldSlug, authorID}
+	visible, visibleArgs := scope.Condition("p")
+	for _, arg := range visibleArgs {
+		args = append(args, arg)
+		visible = strings.Replace(visible, "?", fmt.Sprintf("$%d", len(args)), 1)
+	}
+	if visible != "" {
+		visible = " AND " + visible
+	}
+
+	query := `
+		SELECT p.slug
+		FROM post_slug_history h
+		JOIN post p ON p.id = h.post_id
+		WHERE h.slug = $1 AND ($2 = '' OR h.user_id::text = $2) AND p.deleted_at IS NULL` + visible + `
+		ORDER BY h.created_at DESC
+		LIMIT 1`
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to resolve slug history: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	if !rows.Next() {
+		return "", false, nil
+	}
+	if err := rows.Scan(&current); err != nil {
+		return "", false, fmt.Errorf("failed to scan slug history: %w", err)
+	}
+
+	return current, true, nil
+}
@@ -0,0 +1,32 @@
+package slug
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	invalidChars = regexp.MustCompile(`[^a-z0-9-]+`)
+	hyphenRuns   = regexp.MustCompile(`-+`)
+)
+
+// Make converts a string into a URL-friendly slug
+func Make(s string) string {
+	// Convert to lowercase
+	s = strings.ToLower(s)
+
+	// Replace spaces and underscores with hyphens
+	s = strings.ReplaceAll(s, " ", "-")
+	s = strings.ReplaceAll(s, "_", "-")
+
+	// Remove all non-alphanumeric characters except hyphens
+	s = invalidChars.ReplaceAllString(s, "")
+
+	// Remove multiple consecutive hyphens
+	s = hyphenRuns.ReplaceAllString(s, "-")
+
+	// Trim hyphens from start and end
+	s = strings.Trim(s, "-")
+
+	return s
+}
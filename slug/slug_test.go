@@ -0,0 +1,59 @@
+package slug
+
+import "testing"
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Hello World", "hello-world"},
+		{"  Go_1.22: what's new?  ", "go-122-whats-new"},
+		{"multiple   spaces -- and hyphens", "multiple-spaces-and-hyphens"},
+		{"Ünïcode", "ncode"},
+		{"!!!", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := Make(tt.in); got != tt.want {
+			t.Errorf("Make(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFirstFree(t *testing.T) {
+	tests := []struct {
+		name  string
+		taken []string
+		want  string
+	}{
+		{"free", nil, "hello"},
+		{"other slugs only", []string{"hello-world", "hello-2"}, "hello"},
+		{"taken", []string{"hello"}, "hello-2"},
+		{"suffixes taken", []string{"hello", "hello-2", "hello-3"}, "hello-4"},
+		{"gap in suffixes", []string{"hello", "hello-3"}, "hello-2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			taken := make(map[string]bool)
+			for _, slug := range tt.taken {
+				taken[slug] = true
+			}
+			if got := firstFree("hello", taken); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScopeIsValid(t *testing.T) {
+	for _, scope := range []Scope{ScopeGlobal, ScopeAuthor} {
+		if !scope.IsValid() {
+			t.Errorf("%q is not valid", scope)
+		}
+	}
+	if Scope("team").IsValid() {
+		t.Error(`"team" is valid`)
+	}
+}
@@ -0,0 +1,253 @@
+package taxonomy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/nicolasbonnici/gorest-blog/models"
+	"github.com/nicolasbonnici/gorest-blog/slug"
+	"github.com/nicolasbonnici/gorest/database"
+)
+
+var (
+	ErrUnknownCategory = errors.New("unknown category")
+	ErrUnknownTag      = errors.New("unknown tag")
+)
+
+// Store manages tags, categories and their association with posts.
+type Store struct {
+	db database.Database
+}
+
+func NewStore(db database.Database) *Store {
+	return &Store{db: db}
+}
+
+// EnsureTags returns the tags matching the given names, creating the missing ones.
+// Names are deduplicated on their slug.
+func (s *Store) EnsureTags(ctx context.Context, names []string) ([]models.Tag, error) {
+	query := `
+		INSERT INTO tag (name, slug)
+		VALUES ($1, $2)
+		ON CONFLICT (slug) DO UPDATE SET slug = EXCLUDED.slug
+		RETURNING id, name, slug, updated_at, created_at`
+
+	seen := make(map[string]bool, len(names))
+	tags := make([]models.Tag, 0, len(names))
+	for _, name := range names {
+		tagSlug := slug.Make(name)
+		if tagSlug == "" || seen[tagSlug] {
+			continue
+		}
+		seen[tagSlug] = true
+
+		rows, err := s.db.Query(ctx, query, name, tagSlug)
+		if err != nil {
+			return nil, fmt.Errorf("failed to upsert tag %q: %w", name, err)
+		}
+
+		var tag models.Tag
+		if !rows.Next() {
+			err = rows.Err()
+			_ = rows.Close()
+			if err != nil {
+				return nil, fmt.Errorf("failed to upsert tag %q: %w", name, err)
+			}
+			return nil, fmt.Errorf("no row returned for tag %q", name)
+		}
+		err = rows.Scan(&tag.Id, &tag.Name, &tag.Slug, &tag.UpdatedAt, &tag.CreatedAt)
+		_ = rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan tag %q: %w", name, err)
+		}
+
+		tags = append(tags, tag)
+	}
+
+	return tags, nil
+}
+
+// SetPostTags replaces the tags of a post. Unknown tags are created on the fly when
+// createMissing is true, and rejected with ErrUnknownTag otherwise.
+func (s *Store) SetPostTags(ctx context.Context, postID string, names []string, createMissing bool) ([]models.Tag, error) {
+	var tags []models.Tag
+	var err error
+	if createMissing {
+		tags, err = s.EnsureTags(ctx, names)
+	} else {
+		tags, err = s.existingTags(ctx, names)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tagIDs := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tagIDs = append(tagIDs, tag.Id)
+	}
+
+	if err := s.replaceLinks(ctx, "post_tag", "tag_id", postID, tagIDs); err != nil {
+		return nil, fmt.Errorf("failed to set post tags: %w", err)
+	}
+
+	return tags, nil
+}
+
+// existingTags returns the tags matching the given names, deduplicated on their slug. It
+// fails with ErrUnknownTag when a name matches no tag.
+func (s *Store) existingTags(ctx context.Context, names []string) ([]models.Tag, error) {
+	seen := make(map[string]bool, len(names))
+	slugs := make([]string, 0, len(names))
+	for _, name := range names {
+		tagSlug := slug.Make(name)
+		if tagSlug == "" || seen[tagSlug] {
+			continue
+		}
+		seen[tagSlug] = true
+		slugs = append(slugs, tagSlug)
+	}
+
+	query := `
+		SELECT id, name, slug, updated_at, created_at
+		FROM tag
+		WHERE slug = ANY($1)
+		ORDER BY name`
+
+	tags, err := s.queryTags(ctx, query, slugs)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		found[tag.Slug] = true
+	}
+	for _, tagSlug := range slugs {
+		if !found[tagSlug] {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownTag, tagSlug)
+		}
+	}
+
+	return tags, nil
+}
+
+// PostTags returns the tags attached to a post, ordered by name.
+func (s *Store) PostTags(ctx context.Context, postID string) ([]models.Tag, error) {
+	query := `
+		SELECT t.id, t.name, t.slug, t.updated_at, t.created_at
+		FROM tag t
+		JOIN post_tag pt ON pt.tag_id = t.id
+		WHERE pt.post_id = $1
+		ORDER BY t.name`
+
+	return s.queryTags(ctx, query, postID)
+}
+
+func (s *Store) queryTags(ctx context.Context, query string, args ...any) ([]models.Tag, error) {
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tags: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	tags := make([]models.Tag, 0)
+	for rows.Next() {
+		var tag models.Tag
+		if err := rows.Scan(&tag.Id, &tag.Name, &tag.Slug, &tag.UpdatedAt, &tag.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, nil
+}
+
+// SetPostCategories replaces the categories of a post. Categories are curated,
+// so every slug must reference an existing category.
+func (s *Store) SetPostCategories(ctx context.Context, postID string, slugs []string) ([]models.Category, error) {
+	categories, err := s.categoriesBySlug(ctx, slugs)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]bool, len(categories))
+	categoryIDs := make([]string, 0, len(categories))
+	for _, category := range categories {
+		found[category.Slug] = true
+		categoryIDs = append(categoryIDs, category.Id)
+	}
+	for _, categorySlug := range slugs {
+		if !found[categorySlug] {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownCategory, categorySlug)
+		}
+	}
+
+	if err := s.replaceLinks(ctx, "post_category", "category_id", postID, categoryIDs); err != nil {
+		return nil, fmt.Errorf("failed to set post categories: %w", err)
+	}
+
+	return categories, nil
+}
+
+// PostCategories returns the categories attached to a post, ordered by name.
+func (s *Store) PostCategories(ctx context.Context, postID string) ([]models.Category, error) {
+	query := `
+		SELECT c.id, c.name, c.slug, c.description, c.updated_at, c.created_at
+		FROM category c
+		JOIN post_category pc ON pc.category_id = c.id
+		WHERE pc.post_id = $1
+		ORDER BY c.name`
+
+	return s.queryCategories(ctx, query, postID)
+}
+
+func (s *Store) categoriesBySlug(ctx context.Context, slugs []string) ([]models.Category, error) {
+	query := `
+		SELECT id, name, slug, description, updated_at, created_at
+		FROM category
+		WHERE slug = ANY($1)
+		ORDER BY name`
+
+	return s.queryCategories(ctx, query, slugs)
+}
+
+func (s *Store) queryCategories(ctx context.Context, query string, args ...any) ([]models.Category, error) {
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query categories: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	categories := make([]models.Category, 0)
+	for rows.Next() {
+		var category models.Category
+		if err := rows.Scan(
+			&category.Id,
+			&category.Name,
+			&category.Slug,
+			&category.Description,
+			&category.UpdatedAt,
+			&category.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan category: %w", err)
+		}
+		categories = append(categories, category)
+	}
+
+	return categories, nil
+}
+
+// replaceLinks makes the join table rows of a post match ids exactly, in a single statement.
+func (s *Store) replaceLinks(ctx context.Context, table, column, postID string, ids []string) error {
+	query := fmt.Sprintf(`
+		WITH removed AS (
+			DELETE FROM %[1]s WHERE post_id = $1 AND NOT (%[2]s = ANY($2::uuid[]))
+		)
+		INSERT INTO %[1]s (post_id, %[2]s)
+		SELECT $1, unnest($2::uuid[])
+		ON CONFLICT DO NOTHING`, table, column)
+
+	_, err := s.db.Exec(ctx, query, postID, ids)
+	return err
+}
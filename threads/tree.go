@@ -0,0 +1,142 @@
+package threads
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nicolasbonnici/gorest-blog/models"
+	"github.com/nicolasbonnici/gorest/database"
+)
+
+const (
+	DefaultDepth        = 3
+	MaxDepth            = 10
+	DefaultRepliesLimit = 5
+)
+
+// Node is a comment with the first replies of its subtree.
+type Node struct {
+	models.Comment
+	Depth int `json:"depth"`
+	// ReplyCount is the total number of direct replies, Replies may only hold the first ones.
+	ReplyCount int     `json:"replyCount"`
+	Replies    []*Node `json:"replies"`
+}
+
+// Query selects a page of a comment thread. The page applies to the direct replies of
+// ParentID (top-level comments when nil); each deeper level holds at most RepliesLimit
+// replies, oldest first, down to Depth levels.
+//
+// Only approved comments and the comments written by ViewerID are included, unless
+// AllStatuses is set.
+type Query struct {
+	PostID       string
+	ParentID     *string
+	Depth        int
+	Limit        int
+	Offset       int
+	RepliesLimit int
+	ViewerID     string
+	AllStatuses  bool
+}
+
+// visible returns the visibility condition on comment c, the viewer id and the "all
+// statuses" flag being the arguments number viewerArg and viewerArg+1. Trashed comments
+// are never visible.
+func visible(viewerArg int) string {
+	return fmt.Sprintf("c.deleted_at IS NULL AND ($%d OR c.status = 'approved' OR c.user_id::text = $%d)", viewerArg+1, viewerArg)
+}
+
+type Store struct {
+	db database.Database
+}
+
+func NewStore(db database.Database) *Store {
+	return &Store{db: db}
+}
+
+// Tree returns the requested page of the thread and the total number of comments at its
+// first level.
+func (s *Store) Tree(ctx context.Context, q Query) ([]*Node, int, error) {
+	var total int
+	countQuery := "SELECT COUNT(*) FROM comment c WHERE c.post_id = $1 AND c.parent_id IS NOT DISTINCT FROM $2::uuid AND " + visible(3)
+	if err := s.db.QueryRow(ctx, countQuery, q.PostID, q.ParentID, q.ViewerID, q.AllStatuses).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count comments: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		WITH RECURSIVE tree AS (
+			SELECT * FROM (
+				SELECT c.id, c.user_id, c.post_id, c.parent_id, c.content, c.status, c.like_count, c.updated_at, c.created_at, 1 AS depth
+				FROM comment c
+				WHERE c.post_id = $1 AND c.parent_id IS NOT DISTINCT FROM $2::uuid AND %[1]s
+				ORDER BY c.created_at, c.id
+				LIMIT $3 OFFSET $4
+			) roots
+			UNION ALL
+			SELECT r.id, r.user_id, r.post_id, r.parent_id, r.content, r.status, r.like_count, r.updated_at, r.created_at, t.depth + 1
+			FROM tree t
+			CROSS JOIN LATERAL (
+				SELECT c.*
+				FROM comment c
+				WHERE c.parent_id = t.id AND %[1]s
+				ORDER BY c.created_at, c.id
+				LIMIT $5
+			) r
+			WHERE t.depth < $6
+		)
+		SELECT t.id, t.user_id, t.post_id, t.parent_id, t.content, t.status, t.like_count, t.updated_at, t.created_at, t.depth,
+		       (SELECT COUNT(*) FROM comment c WHERE c.parent_id = t.id AND %[1]s) AS reply_count
+		FROM tree t
+		ORDER BY t.depth, t.created_at, t.id`, visible(7))
+
+	rows, err := s.db.Query(ctx, query,
+		q.PostID, q.ParentID, q.Limit, q.Offset, q.RepliesLimit, q.Depth, q.ViewerID, q.AllStatuses)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to load comment tree: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	// Rows come level by level, so a parent is always known before its replies.
+	roots := make([]*Node, 0)
+	byID := make(map[string]*Node)
+	for rows.Next() {
+		node := &Node{Replies: make([]*Node, 0)}
+		if err := rows.Scan(
+			&node.Id,
+			&node.UserId,
+			&node.PostId,
+			&node.ParentId,
+			&node.Content,
+			&node.Status,
+			&node.LikeCount,
+			&node.UpdatedAt,
+			&node.CreatedAt,
+			&node.Depth,
+			&node.ReplyCount,
+		); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan comment: %w", err)
+		}
+
+		byID[node.Id] = node
+		if node.Depth == 1 {
+			roots = append(roots, node)
+			continue
+		}
+		if parent, ok := byID[*node.ParentId]; ok {
+			parent.Replies = append(parent.Replies, node)
+		}
+	}
+
+	return roots, total, nil
+}
+
+// BelongsToPost reports whether the comment commentID exists and is attached to postID.
+func (s *Store) BelongsToPost(ctx context.Context, commentID, postID string) (bool, error) {
+	var belongs bool
+	query := "SELECT EXISTS(SELECT 1 FROM comment WHERE id::text = $1 AND post_id::text = $2 AND deleted_at IS NULL)"
+	if err := s.db.QueryRow(ctx, query, commentID, postID).Scan(&belongs); err != nil {
+		return false, fmt.Errorf("failed to check parent comment: %w", err)
+	}
+	return belongs, nil
+}
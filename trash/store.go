@@ -0,0 +1,151 @@
+package trash
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nicolasbonnici/gorest/database"
+)
+
+// Trashable targets.
+const (
+	TargetPost    = "post"
+	TargetComment = "comment"
+)
+
+// DefaultRetention is how long trashed posts and comments are kept before being purged.
+const DefaultRetention = 30 * 24 * time.Hour
+
+var (
+	ErrUnknownTarget = errors.New("trashable must be post or comment")
+	ErrNotFound      = errors.New("not found")
+)
+
+// targetTables maps a trashable target to the table holding it.
+var targetTables = map[string]string{
+	TargetPost:    "post",
+	TargetComment: "comment",
+}
+
+// Store moves posts and comments to the trash, restores them and purges them.
+//
+// A trashed row keeps all its data and relations and is only hidden by deleted_at, so
+// restoring it brings back its comments, likes, tags and revisions. Purging a post removes
+// its comments along with it.
+type Store struct {
+	db database.Database
+}
+
+func NewStore(db database.Database) *Store {
+	return &Store{db: db}
+}
+
+// Trash moves the target to the trash. ErrNotFound is returned when it does not exist
+// or is already trashed.
+func (s *Store) Trash(ctx context.Context, target, id string) error {
+	return s.exec(ctx, target, id,
+		"UPDATE %s SET deleted_at = CURRENT_TIMESTAMP WHERE id::text = $1 AND deleted_at IS NULL RETURNING id",
+		"failed to move to trash")
+}
+
+// Restore takes the target out of the trash. ErrNotFound is returned when it is not trashed.
+func (s *Store) Restore(ctx context.Context, target, id string) error {
+	return s.exec(ctx, target, id,
+		"UPDATE %s SET deleted_at = NULL WHERE id::text = $1 AND deleted_at IS NOT NULL RETURNING id",
+		"failed to restore from trash")
+}
+
+// Purge deletes a trashed target for good. ErrNotFound is returned when it is not trashed.
+func (s *Store) Purge(ctx context.Context, target, id string) error {
+	return s.exec(ctx, target, id,
+		"DELETE FROM %s WHERE id::text = $1 AND deleted_at IS NOT NULL RETURNING id",
+		"failed to purge from trash")
+}
+
+// OwnerOf returns the author of a trashed target, nil when it has none.
+func (s *Store) OwnerOf(ctx context.Context, target, id string) (*string, error) {
+	table, ok := targetTables[target]
+	if !ok {
+		return nil, ErrUnknownTarget
+	}
+
+	query := fmt.Sprintf("SELECT user_id FROM %s WHERE id::text = $1 AND deleted_at IS NOT NULL", table)
+	rows, err := s.db.Query(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trashed %s: %w", target, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read trashed %s: %w", target, err)
+		}
+		return nil, ErrNotFound
+	}
+	var owner *string
+	if err := rows.Scan(&owner); err != nil {
+		return nil, fmt.Errorf("failed to scan trashed %s: %w", target, err)
+	}
+	return owner, nil
+}
+
+// purgeQueries delete the rows trashed before $1, comments first. A trashed comment
+// whose replies, at any depth, include a comment that is not purged is kept, so that
+// purging it does not cascade to replies still in use.
+var purgeQueries = []struct{ table, query string }{
+	{"comment", `
+		WITH RECURSIVE kept AS (
+			SELECT id, parent_id FROM comment WHERE deleted_at IS NULL OR deleted_at >= $1
+			UNION
+			SELECT c.id, c.parent_id FROM comment c JOIN kept k ON c.id = k.parent_id
+		)
+		DELETE FROM comment
+		WHERE deleted_at < $1 AND id NOT IN (SELECT id FROM kept)
+		RETURNING id`},
+	{"post", "DELETE FROM post WHERE deleted_at < $1 RETURNING id"},
+}
+
+// PurgeOlderThan deletes for good the comments and posts trashed before cutoff and
+// returns the number of rows removed. Trashed comments with replies that are kept stay in
+// the trash until those replies are purged.
+func (s *Store) PurgeOlderThan(ctx context.Context, cutoff time.Time) (int, error) {
+	purged := 0
+	for _, purge := range purgeQueries {
+		rows, err := s.db.Query(ctx, purge.query, cutoff)
+		if err != nil {
+			return purged, fmt.Errorf("failed to purge %s trash: %w", purge.table, err)
+		}
+		for rows.Next() {
+			purged++
+		}
+		err = rows.Err()
+		_ = rows.Close()
+		if err != nil {
+			return purged, fmt.Errorf("failed to purge %s trash: %w", purge.table, err)
+		}
+	}
+	return purged, nil
+}
+
+func (s *Store) exec(ctx context.Context, target, id, query, failure string) error {
+	table, ok := targetTables[target]
+	if !ok {
+		return ErrUnknownTarget
+	}
+
+	rows, err := s.db.Query(ctx, fmt.Sprintf(query, table), id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", failure, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("%s: %w", failure, err)
+		}
+		return ErrNotFound
+	}
+	return nil
+}
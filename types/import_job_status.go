@@ -0,0 +1,29 @@
+package types
+
+type ImportJobStatus string
+
+const (
+	ImportJobStatusQueued    ImportJobStatus = "queued"
+	ImportJobStatusRunning   ImportJobStatus = "running"
+	ImportJobStatusSucceeded ImportJobStatus = "succeeded"
+	ImportJobStatusFailed    ImportJobStatus = "failed"
+	ImportJobStatusCancelled ImportJobStatus = "cancelled"
+)
+
+func (s ImportJobStatus) String() string {
+	return string(s)
+}
+
+func (s ImportJobStatus) IsValid() bool {
+	switch s {
+	case ImportJobStatusQueued, ImportJobStatusRunning, ImportJobStatusSucceeded, ImportJobStatusFailed, ImportJobStatusCancelled:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsFinished reports whether a job in status s will not run anymore.
+func (s ImportJobStatus) IsFinished() bool {
+	return s == ImportJobStatusSucceeded || s == ImportJobStatusFailed || s == ImportJobStatusCancelled
+}
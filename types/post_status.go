@@ -4,6 +4,7 @@ type PostStatus string
 
 const (
 	PostStatusDrafted   PostStatus = "drafted"
+	PostStatusScheduled PostStatus = "scheduled"
 	PostStatusPublished PostStatus = "published"
 )
 
@@ -13,7 +14,7 @@ func (s PostStatus) String() string {
 
 func (s PostStatus) IsValid() bool {
 	switch s {
-	case PostStatusDrafted, PostStatusPublished:
+	case PostStatusDrafted, PostStatusScheduled, PostStatusPublished:
 		return true
 	default:
 		return false
@@ -0,0 +1,63 @@
+package visibility
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nicolasbonnici/gorest-blog/types"
+)
+
+type scopeKey struct{}
+
+// Scope describes the posts a reader may see: published posts, plus the drafts and
+// scheduled posts written by the reader. Editors and admins see every post.
+//
+// It is applied as a condition combined with the other conditions of a query (e.g. the
+// where clause built by filter.FilterSet), never by rewriting generated SQL.
+type Scope struct {
+	// ViewerID is the authenticated reader, empty for anonymous readers.
+	ViewerID string
+	// AllPosts lets the reader see every post whatever its status and author.
+	AllPosts bool
+}
+
+// WithScope returns a copy of ctx carrying scope, which ForContext returns.
+func WithScope(ctx context.Context, scope Scope) context.Context {
+	return context.WithValue(ctx, scopeKey{}, scope)
+}
+
+// ForContext returns the scope stored in ctx by WithScope. Without one, the user
+// authenticated in ctx only sees published posts and their own.
+func ForContext(ctx context.Context) Scope {
+	if scope, ok := ctx.Value(scopeKey{}).(Scope); ok {
+		return scope
+	}
+	viewerID, _ := ctx.Value("user_id").(string)
+	return Scope{ViewerID: viewerID}
+}
+
+// Public reports whether the scope is the one of anonymous readers, whose responses may
+// be cached by shared caches.
+func (s Scope) Public() bool {
+	return s.ViewerID == "" && !s.AllPosts
+}
+
+// Condition returns the SQL condition selecting the visible posts and its arguments,
+// referenced by "?" markers. alias qualifies the post columns when not empty. The
+// condition is empty when every post is visible.
+func (s Scope) Condition(alias string) (string, []any) {
+	if s.AllPosts {
+		return "", nil
+	}
+
+	prefix := ""
+	if alias != "" {
+		prefix = alias + "."
+	}
+
+	published := types.PostStatusPublished.String()
+	if s.ViewerID == "" {
+		return prefix + "status = ?", []any{published}
+	}
+	return fmt.Sprintf("(%[1]sstatus = ? OR %[1]suser_id = ?)", prefix), []any{published, s.ViewerID}
+}
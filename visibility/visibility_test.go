@@ -0,0 +1,81 @@
+package visibility
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestScopeCondition(t *testing.T) {
+	tests := []struct {
+		name     string
+		scope    Scope
+		alias    string
+		wantCond string
+		wantArgs []any
+	}{
+		{
+			name:     "anonymous",
+			scope:    Scope{},
+			alias:    "p",
+			wantCond: "p.status = ?",
+			wantArgs: []any{"published"},
+		},
+		{
+			name:     "authenticated",
+			scope:    Scope{ViewerID: "user-1"},
+			alias:    "p",
+			wantCond: "(p.status = ? OR p.user_id = ?)",
+			wantArgs: []any{"published", "user-1"},
+		},
+		{
+			name:     "without alias",
+			scope:    Scope{ViewerID: "user-1"},
+			wantCond: "(status = ? OR user_id = ?)",
+			wantArgs: []any{"published", "user-1"},
+		},
+		{
+			name:  "every post",
+			scope: Scope{ViewerID: "user-1", AllPosts: true},
+			alias: "p",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cond, args := tt.scope.Condition(tt.alias)
+			if cond != tt.wantCond {
+				t.Errorf("got condition %q, want %q", cond, tt.wantCond)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("got args %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestScopePublic(t *testing.T) {
+	if !(Scope{}).Public() {
+		t.Error("anonymous scope is not public")
+	}
+	if (Scope{ViewerID: "user-1"}).Public() || (Scope{AllPosts: true}).Public() {
+		t.Error("scope of a reader is public")
+	}
+}
+
+func TestForContext(t *testing.T) {
+	scope := Scope{ViewerID: "user-1", AllPosts: true}
+	if got := ForContext(WithScope(context.Background(), scope)); got != scope {
+		t.Errorf("got %+v, want the scope stored in the context", got)
+	}
+
+	//nolint:staticcheck // gorest stores the authenticated user under a string key.
+	ctx := context.WithValue(context.Background(), "user_id", "user-2")
+	if got := ForContext(ctx); got != (Scope{ViewerID: "user-2"}) {
+		t.Errorf("got %+v, want the scope of the authenticated user", got)
+	}
+
+	if got := ForContext(context.Background()); got != (Scope{}) {
+		t.Errorf("got %+v, want the anonymous scope", got)
+	}
+}